@@ -62,6 +62,13 @@ resource "aws_apigatewayv2_route" "trigger_step_function" {
   target    = "integrations/${aws_apigatewayv2_integration.trigger_step_function.id}"
 }
 
+// Resumes a watch which stopped on verification of the Ryanair device, body is the original /start request with device, mfa_token and mfa_code.
+resource "aws_apigatewayv2_route" "verify_step_function" {
+  api_id    = aws_apigatewayv2_api.seatchecker_api.id
+  route_key = "POST /verify"
+  target    = "integrations/${aws_apigatewayv2_integration.trigger_step_function.id}"
+}
+
 resource "aws_apigatewayv2_integration" "stop_step_function" {
   api_id                 = aws_apigatewayv2_api.seatchecker_api.id
   description            = "Stop Step Functions Execution"
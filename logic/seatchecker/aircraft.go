@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// Names of the equipment models, used in notifications.
+var aircraftNames = map[string]string{
+	"73H": "Boeing 737-800",
+	"7M8": "Boeing 737-8200",
+	"320": "Airbus A320",
+}
+
+// Aircraft operating the tracked flight.
+type Aircraft struct {
+	Model string `json:"model"`
+	Rows  int    `json:"rows"`
+}
+
+func (ac Aircraft) name() string {
+	if n, ok := aircraftNames[ac.Model]; ok {
+		return n
+	}
+	return ac.Model
+}
+
+// Every row is counted with six seats, as in calculation of empty seats.
+func (ac Aircraft) capacity() int {
+	return ac.Rows * len(seatColumns)
+}
+
+// Airline swapped the aircraft of the flight, e.g. 737-800 for a 737-8200.
+func aircraftChanged(prev Aircraft, curr Aircraft) bool {
+	return prev.Model != "" && prev.Model != curr.Model
+}
+
+// Describe the swap, e.g. "Aircraft changed from Boeing 737-800 (192 seats) to Boeing 737-8200 (198 seats)".
+func aircraftChangeText(prev Aircraft, curr Aircraft, es EmptySeats) string {
+	return fmt.Sprintf("Aircraft changed from %v (%v seats) to %v (%v seats)\n%v",
+		prev.name(), prev.capacity(), curr.name(), curr.capacity(), es.generateText())
+}
+
+// Previous seats belong to the other aircraft, the new one becomes the baseline of the watch.
+func (e *Event) resetBaseline(es EmptySeats) {
+	e.SeatState = es
+	e.History = nil
+	e.Group = GroupFit{}
+	e.PreferredFree = nil
+	e.Unavailable = nil
+	for k, d := range e.Deliveries {
+		d.Seats = es
+		e.Deliveries[k] = d
+	}
+}
+
+// Let every subscriber know about the swap, regardless of their rules.
+func (s Seatchecker) sendAircraftChange(ctx context.Context, e Event, prev Aircraft, es EmptySeats) {
+	m := Message{
+		Title: "Seatchecker: aircraft changed",
+		Text:  aircraftChangeText(prev, e.Aircraft, es),
+	}
+	if fTxt := e.Flight.generateText(); fTxt != "" {
+		m.Text = fTxt + " — " + m.Text
+	}
+	if _, err := s.fanOut(ctx, e.subscribers(), m); err != nil {
+		log.Printf("Error: failed to send aircraft change: %v\n", err)
+	}
+}
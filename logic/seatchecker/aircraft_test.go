@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestAircraftChanged(t *testing.T) {
+	test := func(prev string, curr string, e bool) {
+		if r := aircraftChanged(Aircraft{Model: prev}, Aircraft{Model: curr}); r != e {
+			t.Fatalf("wrong detection of %v -> %v, expected: %v, received: %v", prev, curr, e, r)
+		}
+	}
+
+	test("", "73H", false) // First run.
+	test("73H", "73H", false)
+	test("73H", "7M8", true)
+}
+
+func TestAircraftChangeText(t *testing.T) {
+	e := "Aircraft changed from Boeing 737-800 (192 seats) to Boeing 737-8200 (198 seats)\nWindow: 10, Middle: 4, Aisle: 8"
+	r := aircraftChangeText(Aircraft{"73H", 32}, Aircraft{"7M8", 33}, EmptySeats{10, 4, 8})
+	if e != r {
+		t.Fatalf("wrong output, expected: %v, received: %v", e, r)
+	}
+
+	e = "Aircraft changed from Boeing 737-800 (192 seats) to 32B (186 seats)\nWindow: 0, Middle: 0, Aisle: 0"
+	r = aircraftChangeText(Aircraft{"73H", 32}, Aircraft{"32B", 31}, EmptySeats{})
+	if e != r {
+		t.Fatalf("wrong output, expected: %v, received: %v", e, r)
+	}
+}
+
+func TestResetBaseline(t *testing.T) {
+	es := EmptySeats{10, 4, 8}
+	e := Event{
+		SeatState:     EmptySeats{12, 6, 10},
+		History:       []Sample{{"2024-07-01T12:00:00Z", EmptySeats{12, 6, 10}}},
+		Group:         GroupFit{Passengers: 2},
+		PreferredFree: []string{"01A"},
+		Unavailable:   []string{"02B"},
+		Deliveries:    map[string]Delivery{"sub": {Time: "2024-07-01T12:00:00Z", Seats: EmptySeats{12, 6, 10}}},
+	}
+	e.resetBaseline(es)
+
+	if e.SeatState != es || e.History != nil || e.Group.Passengers != 0 || e.PreferredFree != nil || e.Unavailable != nil {
+		t.Fatalf("baseline not reset: %+v", e)
+	}
+	// Rate limiting continues on the new aircraft.
+	if d := e.Deliveries["sub"]; d.Time != "2024-07-01T12:00:00Z" || d.Seats != es {
+		t.Fatalf("wrong delivery, received: %v", d)
+	}
+}
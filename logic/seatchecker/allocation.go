@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Probability of receiving a window or aisle seat when checking in now.
+type AllocationEstimate struct {
+	Model string  `json:"model"`
+	Seat  float64 `json:"seat"` // Single passenger receives window or aisle seat.
+	All   float64 `json:"all"`  // Every passenger of the booking receives window or aisle seat.
+}
+
+// Every empty seat is equally likely to be allocated.
+func uniformAllocation(es EmptySeats, n int) AllocationEstimate {
+	ae := AllocationEstimate{Model: "uniform"}
+	t := es.Window + es.Middle + es.Aisle
+	g := es.Window + es.Aisle
+	if t == 0 {
+		return ae
+	}
+
+	ae.Seat = float64(g) / float64(t)
+	// Passengers are drawn without replacement.
+	ae.All = 1
+	for i := 0; i < n; i++ {
+		if g-i <= 0 {
+			ae.All = 0
+			break
+		}
+		ae.All *= float64(g-i) / float64(t-i)
+	}
+	return ae
+}
+
+// Middle seats are allocated first, the rest of passengers gets window or aisle seat.
+func middleFirstAllocation(es EmptySeats, n int) AllocationEstimate {
+	ae := AllocationEstimate{Model: "middle_first"}
+	g := min(max(n-es.Middle, 0), es.Window+es.Aisle)
+
+	ae.Seat = float64(g) / float64(n)
+	if g == n {
+		ae.All = 1
+	}
+	return ae
+}
+
+// Estimate allocation for the passengers of booking under several allocation models.
+func estimateAllocation(es EmptySeats, passengers int) []AllocationEstimate {
+	if passengers <= 0 {
+		return nil
+	}
+	return []AllocationEstimate{
+		uniformAllocation(es, passengers),
+		middleFirstAllocation(es, passengers),
+	}
+}
+
+func percentage(p float64) string {
+	return fmt.Sprintf("%v%%", math.Round(p*100))
+}
+
+func allocationText(aes []AllocationEstimate) string {
+	var ps []string
+	for _, ae := range aes {
+		ps = append(ps, fmt.Sprintf("%v %v", percentage(ae.Seat), strings.ReplaceAll(ae.Model, "_", "-")))
+	}
+	return "Window/Aisle chance: " + strings.Join(ps, ", ")
+}
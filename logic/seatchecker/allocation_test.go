@@ -0,0 +1,52 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEstimateAllocation(t *testing.T) {
+	test := func(es EmptySeats, n int, m string, seat float64, all float64) {
+		for _, ae := range estimateAllocation(es, n) {
+			if ae.Model != m {
+				continue
+			}
+			if math.Abs(ae.Seat-seat) > 0.001 || math.Abs(ae.All-all) > 0.001 {
+				t.Fatalf("wrong %v estimate for %v, expected: %v/%v, received: %v/%v",
+					m, es.generateText(), seat, all, ae.Seat, ae.All)
+			}
+			return
+		}
+		t.Fatalf("missing estimate for model: %v", m)
+	}
+
+	es := EmptySeats{Window: 2, Middle: 4, Aisle: 2}
+	test(es, 1, "uniform", 0.5, 0.5)
+	// 4/8 * 3/7
+	test(es, 2, "uniform", 0.5, 0.214)
+	test(es, 5, "uniform", 0.5, 0)
+	test(es, 2, "middle_first", 0, 0)
+	// Two passengers get remaining middle seats.
+	test(es, 6, "middle_first", 2.0/6, 0)
+	test(EmptySeats{Window: 3, Middle: 0, Aisle: 1}, 2, "middle_first", 1, 1)
+	test(EmptySeats{}, 1, "uniform", 0, 0)
+
+	if aes := estimateAllocation(es, 0); aes != nil {
+		t.Fatalf("expected no estimates for booking without passengers, received: %v", aes)
+	}
+}
+
+func TestSeatedPassengers(t *testing.T) {
+	ps := []Passenger{{1, "ADT"}, {2, "CHD"}, {3, "INF"}}
+	if r := seatedPassengers(ps); r != 2 {
+		t.Fatalf("wrong number of seated passengers, expected: 2, received: %v", r)
+	}
+}
+
+func TestAllocationText(t *testing.T) {
+	e := "Window/Aisle chance: 63% uniform, 0% middle-first"
+	r := allocationText([]AllocationEstimate{{"uniform", 0.625, 0.625}, {"middle_first", 0, 0}})
+	if e != r {
+		t.Fatalf("wrong output, expected: %v, received: %v", e, r)
+	}
+}
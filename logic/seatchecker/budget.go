@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Steps of the run with their share of the time budget.
+const (
+	stepLogin    = "login"
+	stepBookings = "bookings"
+	stepTrip     = "trip" // Every booking has its own share, they are fetched concurrently.
+	stepBasket   = "basket"
+	stepSeatMap  = "seat_map"
+)
+
+var budgetShares = map[string]float64{
+	stepLogin:    0.15,
+	stepBookings: 0.15,
+	stepTrip:     0.2,
+	stepBasket:   0.3,
+	stepSeatMap:  0.2,
+}
+
+// Time left for sending notifications after the airline is queried.
+const budgetReserve = 2 * time.Second
+
+// Budget of runs without deadline, e.g. running locally.
+const defaultBudget = 30 * time.Second
+
+// Time available for the airline calls, derived from the remaining time of the Lambda.
+// Steps are limited by their share and by the deadline of the whole budget,
+// so queued bookings or repeated steps can not overrun it.
+type Budget struct {
+	total    time.Duration
+	deadline time.Time // Zero without deadline of the context, e.g. running locally.
+}
+
+type budgetKey struct{}
+
+// Lambda sets the deadline of the context to its timeout.
+// Deadline is in real time, the clock of the handler does not apply to it.
+func newBudget(ctx context.Context) Budget {
+	d, ok := ctx.Deadline()
+	if !ok {
+		return Budget{total: defaultBudget}
+	}
+	return Budget{total: max(time.Until(d)-budgetReserve, 0), deadline: d.Add(-budgetReserve)}
+}
+
+func (b Budget) limit(step string) time.Duration {
+	return time.Duration(float64(b.total) * budgetShares[step])
+}
+
+func withBudget(ctx context.Context, b Budget) context.Context {
+	return context.WithValue(ctx, budgetKey{}, b)
+}
+
+// Step which did not finish within its share of the budget.
+type BudgetError struct {
+	Step   string
+	Budget time.Duration
+	Err    error
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("step %v exceeded its budget of %v: %v", e.Step, e.Budget, e.Err)
+}
+
+func (e *BudgetError) Unwrap() error {
+	return e.Err
+}
+
+// Run the step within its share of the budget, context without budget is not limited.
+func runStep[T any](ctx context.Context, step string, fn func(ctx context.Context) (T, error)) (T, error) {
+	b, ok := ctx.Value(budgetKey{}).(Budget)
+	if !ok {
+		return fn(ctx)
+	}
+	l := b.limit(step)
+	sctx, cancel := context.WithTimeout(ctx, l)
+	defer cancel()
+	if !b.deadline.IsZero() {
+		sctx, cancel = context.WithDeadline(sctx, b.deadline)
+		defer cancel()
+	}
+
+	t, err := fn(sctx)
+	if err != nil && errors.Is(sctx.Err(), context.DeadlineExceeded) {
+		trace.SpanFromContext(ctx).AddEvent("Step exceeded its budget.", trace.WithAttributes(
+			attribute.String("step", step),
+			attribute.String("budget", l.String())))
+		return t, &BudgetError{Step: step, Budget: l, Err: err}
+	}
+	return t, err
+}
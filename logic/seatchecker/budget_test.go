@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewBudget(t *testing.T) {
+	now := time.Now()
+	// Budget shrinks while the test runs.
+	test := func(ctx context.Context, e time.Duration) {
+		if b := newBudget(ctx); b.total > e || b.total < e-time.Second {
+			t.Fatalf("wrong budget, expected: %v, received: %v", e, b.total)
+		}
+	}
+
+	test(context.Background(), defaultBudget)
+
+	ctx, cancel := context.WithDeadline(context.Background(), now.Add(10*time.Second))
+	defer cancel()
+	test(ctx, 8*time.Second)
+	if b := newBudget(ctx); !b.deadline.Equal(now.Add(8 * time.Second)) {
+		t.Fatalf("wrong deadline, expected: %v, received: %v", now.Add(8*time.Second), b.deadline)
+	}
+
+	// Nothing left after the reserve.
+	ctx, cancel = context.WithDeadline(context.Background(), now.Add(time.Second))
+	defer cancel()
+	test(ctx, 0)
+
+	b := Budget{total: 10 * time.Second}
+	if l := b.limit(stepBasket); l != 3*time.Second {
+		t.Fatalf("wrong limit of basket, expected: 3s, received: %v", l)
+	}
+
+	// Shares split the budget, none of it is spent twice.
+	sum := 0.0
+	for _, s := range budgetShares {
+		sum += s
+	}
+	if sum < 0.999 || sum > 1.001 {
+		t.Fatalf("shares do not split the budget, received sum: %v", sum)
+	}
+}
+
+func TestRunStep(t *testing.T) {
+	slow := func(ctx context.Context) (int, error) {
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+			return 1, nil
+		}
+	}
+
+	// Without budget the step is not limited.
+	if r, err := runStep(context.Background(), stepLogin, slow); err != nil || r != 1 {
+		t.Fatalf("unlimited step failed: %v, %v", r, err)
+	}
+
+	ctx := withBudget(context.Background(), Budget{total: 100 * time.Millisecond})
+	_, err := runStep(ctx, stepLogin, slow)
+	var be *BudgetError
+	if !errors.As(err, &be) || be.Step != stepLogin || be.Budget != 15*time.Millisecond {
+		t.Fatalf("expected budget error of login, received: %v", err)
+	}
+
+	// Step within its share is limited by the deadline of the whole budget.
+	start := time.Now()
+	dctx := withBudget(context.Background(), Budget{total: time.Second, deadline: start.Add(20 * time.Millisecond)})
+	_, err = runStep(dctx, stepBasket, slow)
+	if !errors.As(err, &be) || time.Since(start) > 150*time.Millisecond {
+		t.Fatalf("expected budget error of basket at the deadline, received: %v", err)
+	}
+
+	// Errors of the step itself are kept.
+	fail := func(ctx context.Context) (int, error) {
+		return 0, errors.New("invalid credentials")
+	}
+	_, err = runStep(ctx, stepLogin, fail)
+	if err == nil || errors.As(err, &be) {
+		t.Fatalf("expected error of the step, received: %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Layout of an aircraft model does not change, the TTL only refreshes renumbered cabins.
+const seatMapTTL = 30 * 24 * time.Hour
+
+// Number of rows of Ryanair aircraft, used only when their seatmap endpoint fails.
+var ryanairLayouts = map[string]int{
+	"73H": 32, // Boeing 737-800.
+	"7M8": 33, // Boeing 737-8200.
+	"320": 30, // Airbus A320.
+}
+
+type seatMapEntry struct {
+	Rows    int       `json:"rows"`
+	Expires time.Time `json:"expires"`
+}
+
+func (en seatMapEntry) fresh(now time.Time) bool {
+	return now.Before(en.Expires)
+}
+
+// Number of rows by equipment model of a single airline.
+// Kept in memory of the warm Lambda and mirrored to a file, which survives until the container is recycled.
+// Known layouts are not trusted over the endpoint, cold container queries it once per model.
+type SeatMapCache struct {
+	mu      sync.Mutex
+	clock   Clock
+	path    string // Empty disables the file.
+	entries map[string]seatMapEntry
+	seeds   map[string]int // Known layouts, fallback of failing endpoint.
+}
+
+func newSeatMapCache(clock Clock, path string, seeds map[string]int) *SeatMapCache {
+	c := &SeatMapCache{clock: clock, path: path, entries: map[string]seatMapEntry{}, seeds: seeds}
+	if err := c.load(); err != nil {
+		log.Printf("Error: failed to load seat map cache: %v\n", err)
+	}
+	return c
+}
+
+func (c *SeatMapCache) load() error {
+	if c.path == "" {
+		return nil
+	}
+	b, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read file: %v", err)
+	}
+	if err := json.Unmarshal(b, &c.entries); err != nil {
+		return fmt.Errorf("failed to unmarshal file: %v", err)
+	}
+	return nil
+}
+
+func (c *SeatMapCache) save() error {
+	if c.path == "" {
+		return nil
+	}
+	b, err := json.Marshal(c.entries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal entries: %v", err)
+	}
+	if err := os.WriteFile(c.path, b, 0o600); err != nil {
+		return fmt.Errorf("failed to write file: %v", err)
+	}
+	return nil
+}
+
+func (c *SeatMapCache) get(model string) (seatMapEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	en, ok := c.entries[model]
+	return en, ok
+}
+
+func (c *SeatMapCache) put(model string, rows int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[model] = seatMapEntry{Rows: rows, Expires: c.clock.Now().Add(seatMapTTL)}
+	if err := c.save(); err != nil {
+		log.Printf("Error: failed to save seat map cache: %v\n", err)
+	}
+}
+
+// Number of rows of the aircraft, the seatmap endpoint is queried only for unknown or expired models.
+// Expired entry or known layout is used when the endpoint fails.
+func (c *SeatMapCache) rows(ctx context.Context, p SeatProvider, model string) (int, error) {
+	if c == nil {
+		return p.seatMap(ctx, model)
+	}
+	ctx, span := tr.Start(ctx, "cached_seat_map")
+	defer span.End()
+	span.SetAttributes(attribute.String("model", model))
+
+	en, ok := c.get(model)
+	if ok && en.fresh(c.clock.Now()) {
+		span.SetAttributes(attribute.Bool("cache_hit", true))
+		return en.Rows, nil
+	}
+	span.SetAttributes(attribute.Bool("cache_hit", false))
+
+	rows, err := p.seatMap(ctx, model)
+	if err != nil {
+		if ok {
+			log.Printf("Error: seatmap failed, using expired layout of %v: %v\n", model, err)
+			span.AddEvent("Expired layout used as fallback.")
+			return en.Rows, nil
+		}
+		if rows, ok := c.seeds[model]; ok {
+			log.Printf("Error: seatmap failed, using known layout of %v: %v\n", model, err)
+			span.AddEvent("Known layout used as fallback.")
+			return rows, nil
+		}
+		return 0, err
+	}
+	c.put(model, rows)
+	return rows, nil
+}
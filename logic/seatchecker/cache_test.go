@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Provider serving only the seatmap, counting the calls.
+type stubSeatMap struct {
+	rows  int
+	err   error
+	calls int
+}
+
+func (sm *stubSeatMap) login(ctx context.Context, email string, password string, device string) (Auth, error) {
+	return Auth{}, nil
+}
+
+func (sm *stubSeatMap) bookings(ctx context.Context, a Auth) ([]string, error) {
+	return nil, nil
+}
+
+func (sm *stubSeatMap) trip(ctx context.Context, a Auth, id string) (TripInfo, error) {
+	return TripInfo{}, nil
+}
+
+func (sm *stubSeatMap) occupiedSeats(ctx context.Context, a Auth, ti TripInfo, j Journey, ss Session) (FlightInfo, Session, error) {
+	return FlightInfo{}, Session{}, nil
+}
+
+func (sm *stubSeatMap) seatMap(ctx context.Context, model string) (int, error) {
+	sm.calls += 1
+	return sm.rows, sm.err
+}
+
+func TestSeatMapCache(t *testing.T) {
+	ctx := context.Background()
+	c := &fakeClock{time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)}
+	path := filepath.Join(t.TempDir(), "seatmaps.json")
+	sm := &stubSeatMap{rows: 31}
+	sc := newSeatMapCache(c, path, ryanairLayouts)
+
+	test := func(sc *SeatMapCache, model string, e int, calls int) {
+		r, err := sc.rows(ctx, sm, model)
+		if err != nil {
+			t.Fatalf("failed to get rows of %v: %v", model, err)
+		}
+		if r != e {
+			t.Fatalf("wrong rows of %v, expected: %v, received: %v", model, e, r)
+		}
+		if sm.calls != calls {
+			t.Fatalf("wrong number of seatmap calls, expected: %v, received: %v", calls, sm.calls)
+		}
+	}
+
+	// Known layout is fetched as any other, the endpoint is the source of truth.
+	test(sc, "73H", 31, 1)
+	test(sc, "73H", 31, 1)
+
+	// Unknown layout is fetched once.
+	test(sc, "321", 31, 2)
+	test(sc, "321", 31, 2)
+
+	// File keeps the layout for a cold start.
+	test(newSeatMapCache(c, path, nil), "321", 31, 2)
+
+	// Expired layout is refreshed.
+	c.now = c.now.Add(seatMapTTL)
+	sm.rows = 33
+	test(sc, "321", 33, 3)
+
+	// Expired layout is a fallback for failing endpoint.
+	c.now = c.now.Add(seatMapTTL)
+	sm.err = errors.New("seatmap down")
+	test(sc, "321", 33, 4)
+
+	// Known layout is a fallback when nothing was fetched yet.
+	test(newSeatMapCache(c, "", ryanairLayouts), "7M8", 33, 5)
+
+	if _, err := sc.rows(ctx, sm, "DH4"); err == nil {
+		t.Fatalf("expected error for unknown layout and failing endpoint")
+	}
+
+	// Missing cache always queries the endpoint.
+	var nc *SeatMapCache
+	sm.err = nil
+	test(nc, "73H", 33, 7)
+}
+
+func TestSeatMapCacheSeeds(t *testing.T) {
+	ctx := context.Background()
+	c := &fakeClock{time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)}
+	sm := &stubSeatMap{rows: 32}
+	sc := newSeatMapCache(c, "", map[string]int{"73H": 31})
+
+	// Known layout is only a fallback, the endpoint is queried first.
+	if r, err := sc.rows(ctx, sm, "73H"); err != nil || r != 32 || sm.calls != 1 {
+		t.Fatalf("known layout used over the endpoint, received: %v, %v, calls: %v", r, err, sm.calls)
+	}
+
+	sm.err = errors.New("seatmap down")
+	sc = newSeatMapCache(c, "", map[string]int{"73H": 31})
+	if r, err := sc.rows(ctx, sm, "73H"); err != nil || r != 31 {
+		t.Fatalf("known layout not used for failing endpoint, received: %v, %v", r, err)
+	}
+}
@@ -0,0 +1,14 @@
+package main
+
+import "time"
+
+// Source of current time, replaced in tests to control time dependent logic.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now().UTC()
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Number of seats listed in compact notification.
+const compactSeats = 5
+
+// Seats which changed their availability since the previous run.
+type SeatDiff struct {
+	Taken    []string `json:"taken"`
+	Released []string `json:"released"`
+}
+
+func diffSeats(prev []string, curr []string) SeatDiff {
+	d := SeatDiff{}
+	for _, s := range curr {
+		if !slices.Contains(prev, s) {
+			d.Taken = append(d.Taken, s)
+		}
+	}
+	for _, s := range prev {
+		if !slices.Contains(curr, s) {
+			d.Released = append(d.Released, s)
+		}
+	}
+	slices.Sort(d.Taken)
+	slices.Sort(d.Released)
+	return d
+}
+
+func (d SeatDiff) empty() bool {
+	return len(d.Taken) == 0 && len(d.Released) == 0
+}
+
+func validateDiffStyle(s string) error {
+	switch s {
+	case "", "compact", "verbose":
+		return nil
+	default:
+		return fmt.Errorf("unknown diff style: %v", s)
+	}
+}
+
+// Count of seats by type, e.g. "3 middle, 4 aisle".
+func seatTypesText(ss []string) string {
+	es := EmptySeats{}
+	for _, s := range ss {
+		switch seatType(s[len(s)-1:]) {
+		case "window":
+			es.Window += 1
+		case "middle":
+			es.Middle += 1
+		case "aisle":
+			es.Aisle += 1
+		}
+	}
+
+	var ts []string
+	for _, t := range []struct {
+		name  string
+		count int
+	}{{"window", es.Window}, {"middle", es.Middle}, {"aisle", es.Aisle}} {
+		if t.count > 0 {
+			ts = append(ts, fmt.Sprintf("%v %v", t.count, t.name))
+		}
+	}
+	return strings.Join(ts, ", ")
+}
+
+func seatListText(ss []string, verbose bool) string {
+	if verbose || len(ss) <= compactSeats {
+		return strings.Join(ss, ", ")
+	}
+	return strings.Join(ss[:compactSeats], ", ") + "…"
+}
+
+// Describe the change, e.g. "+7 taken: 3 middle, 4 aisle; newly taken 12B, 14E…".
+func (d SeatDiff) generateText(style string) string {
+	verbose := style == "verbose"
+
+	var ps []string
+	if len(d.Taken) > 0 {
+		ps = append(ps, fmt.Sprintf("+%v taken: %v; newly taken %v",
+			len(d.Taken), seatTypesText(d.Taken), seatListText(d.Taken, verbose)))
+	}
+	if len(d.Released) > 0 {
+		if verbose {
+			ps = append(ps, fmt.Sprintf("-%v released: %v; released %v",
+				len(d.Released), seatTypesText(d.Released), seatListText(d.Released, verbose)))
+		} else {
+			ps = append(ps, fmt.Sprintf("-%v released", len(d.Released)))
+		}
+	}
+	return strings.Join(ps, "\n")
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffSeats(t *testing.T) {
+	prev := []string{"01A", "03C", "12B"}
+	curr := []string{"14E", "01A", "12B", "02F"}
+
+	e := SeatDiff{Taken: []string{"02F", "14E"}, Released: []string{"03C"}}
+	if r := diffSeats(prev, curr); !reflect.DeepEqual(e, r) {
+		t.Fatalf("wrong diff, expected: %v, received: %v", e, r)
+	}
+	if r := diffSeats(prev, prev); !r.empty() {
+		t.Fatalf("expected empty diff, received: %v", r)
+	}
+}
+
+func TestSeatDiffText(t *testing.T) {
+	d := SeatDiff{
+		Taken:    []string{"02B", "05D", "07E", "09C", "10B", "12B", "14E"},
+		Released: []string{"03A", "04C"},
+	}
+
+	test := func(style string, e string) {
+		if r := d.generateText(style); r != e {
+			t.Fatalf("wrong output, expected: %v, received: %v", e, r)
+		}
+	}
+
+	test("compact", "+7 taken: 5 middle, 2 aisle; newly taken 02B, 05D, 07E, 09C, 10B…\n-2 released")
+	test("verbose", "+7 taken: 5 middle, 2 aisle; newly taken 02B, 05D, 07E, 09C, 10B, 12B, 14E\n"+
+		"-2 released: 1 window, 1 aisle; released 03A, 04C")
+
+	if err := validateDiffStyle("fancy"); err == nil {
+		t.Fatal("expected error for unknown diff style")
+	}
+}
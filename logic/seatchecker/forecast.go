@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Number of samples kept in history, roughly two hours of polling.
+const historySize = 12
+
+type Sample struct {
+	Time  string     `json:"time"`
+	Seats EmptySeats `json:"seats"`
+}
+
+// Append current snapshot to the history and drop the oldest samples.
+func recordSample(h []Sample, now time.Time, es EmptySeats) []Sample {
+	h = append(h, Sample{Time: now.UTC().Format(time.RFC3339), Seats: es})
+	if len(h) > historySize {
+		h = h[len(h)-historySize:]
+	}
+	return h
+}
+
+// Fit returns time (in seconds from the first sample) when the fitted curve reaches zero.
+type fit func(xs []float64, ys []float64) (float64, bool)
+
+func forecastModel(m string) (fit, error) {
+	switch m {
+	case "", "linear":
+		return linearFit, nil
+	case "exponential":
+		return exponentialFit, nil
+	default:
+		return nil, fmt.Errorf("unknown forecast model: %v", m)
+	}
+}
+
+// Least squares fit of a line, returns intercept and slope.
+func leastSquares(xs []float64, ys []float64) (float64, float64, bool) {
+	n := float64(len(xs))
+	var sx, sy, sxx, sxy float64
+	for i := range xs {
+		sx += xs[i]
+		sy += ys[i]
+		sxx += xs[i] * xs[i]
+		sxy += xs[i] * ys[i]
+	}
+	d := n*sxx - sx*sx
+	if d == 0 {
+		return 0, 0, false
+	}
+	b := (n*sxy - sx*sy) / d
+	a := (sy - b*sx) / n
+	return a, b, true
+}
+
+func linearFit(xs []float64, ys []float64) (float64, bool) {
+	a, b, ok := leastSquares(xs, ys)
+	if !ok || b >= 0 {
+		return 0, false
+	}
+	return -a / b, true
+}
+
+// Exponential decay never reaches zero, the last seat is gone when less than half of it remains.
+func exponentialFit(xs []float64, ys []float64) (float64, bool) {
+	var lxs, lys []float64
+	for i := range ys {
+		if ys[i] <= 0 {
+			continue
+		}
+		lxs = append(lxs, xs[i])
+		lys = append(lys, math.Log(ys[i]))
+	}
+	a, b, ok := leastSquares(lxs, lys)
+	if !ok || b >= 0 {
+		return 0, false
+	}
+	return (math.Log(0.5) - a) / b, true
+}
+
+// Estimate how long it takes until the picked category of seats is fully taken.
+func forecastDepletion(h []Sample, pick func(EmptySeats) int, f fit, now time.Time) (time.Duration, bool) {
+	if len(h) < 2 || pick(h[len(h)-1].Seats) == 0 {
+		return 0, false
+	}
+
+	var t0 time.Time
+	var xs, ys []float64
+	for _, s := range h {
+		t, err := time.Parse(time.RFC3339, s.Time)
+		if err != nil {
+			continue
+		}
+		if t0.IsZero() {
+			t0 = t
+		}
+		xs = append(xs, t.Sub(t0).Seconds())
+		ys = append(ys, float64(pick(s.Seats)))
+	}
+
+	x, ok := f(xs, ys)
+	if !ok {
+		return 0, false
+	}
+	eta := t0.Add(time.Duration(x * float64(time.Second))).Sub(now)
+	return max(eta, 0), true
+}
+
+func middleSeats(es EmptySeats) int {
+	return es.Middle
+}
+
+// Format duration rounded to minutes, e.g. "1h40m".
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	h := int(d.Hours())
+	m := int(d.Minutes()) % 60
+	if h == 0 {
+		return fmt.Sprintf("%dm", m)
+	}
+	return fmt.Sprintf("%dh%02dm", h, m)
+}
+
+// Format estimate as a rough duration, e.g. "~1h40m".
+func formatETA(d time.Duration) string {
+	if d.Round(time.Minute) < time.Minute {
+		return "<1m"
+	}
+	return "~" + formatDuration(d)
+}
+
+// Notification text including estimate when middle seats run out.
+func forecastText(es EmptySeats, eta time.Duration, ok bool) string {
+	if !ok {
+		return es.generateText()
+	}
+	return fmt.Sprintf("Window: %v, Middle: %v, expected 0 in %v, Aisle: %v",
+		es.Window, es.Middle, formatETA(eta), es.Aisle)
+}
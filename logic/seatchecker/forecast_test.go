@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordSample(t *testing.T) {
+	n := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	var h []Sample
+	for i := 0; i < historySize+3; i++ {
+		h = recordSample(h, n.Add(time.Duration(i)*time.Minute), EmptySeats{i, i, i})
+	}
+	if len(h) != historySize {
+		t.Fatalf("wrong history size, expected: %v, received: %v", historySize, len(h))
+	}
+	if h[0].Seats.Middle != 3 {
+		t.Fatalf("oldest samples were not dropped, received: %v", h[0])
+	}
+	if h[len(h)-1].Time != "2024-07-01T12:14:00Z" {
+		t.Fatalf("wrong time of last sample, received: %v", h[len(h)-1].Time)
+	}
+}
+
+func TestForecastDepletion(t *testing.T) {
+	n := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	history := func(ms ...int) []Sample {
+		var h []Sample
+		for i, m := range ms {
+			h = recordSample(h, n.Add(time.Duration(i*10)*time.Minute), EmptySeats{Middle: m})
+		}
+		return h
+	}
+	test := func(h []Sample, m string, e time.Duration, eok bool) {
+		f, err := forecastModel(m)
+		if err != nil {
+			t.Fatalf("failed to get forecast model: %v", err)
+		}
+		now, _ := time.Parse(time.RFC3339, h[len(h)-1].Time)
+		r, ok := forecastDepletion(h, middleSeats, f, now)
+		if ok != eok {
+			t.Fatalf("wrong forecast availability, expected: %v, received: %v", eok, ok)
+		}
+		if r.Round(time.Minute) != e {
+			t.Fatalf("wrong forecast, expected: %v, received: %v", e, r)
+		}
+	}
+
+	// Two seats taken every 10 minutes.
+	test(history(12, 10, 8, 6), "linear", 30*time.Minute, true)
+	// Half of the seats taken every 10 minutes.
+	test(history(16, 8, 4, 2), "exponential", 20*time.Minute, true)
+	// Seats are not being taken.
+	test(history(12, 12, 12), "linear", 0, false)
+	test(history(12, 14), "exponential", 0, false)
+	// Not enough samples.
+	test(history(12), "linear", 0, false)
+	// Middle seats already ran out.
+	test(history(4, 2, 0), "linear", 0, false)
+
+	if _, err := forecastModel("quadratic"); err == nil {
+		t.Fatal("expected error for unknown forecast model")
+	}
+}
+
+func TestForecastText(t *testing.T) {
+	test := func(eta time.Duration, ok bool, e string) {
+		r := forecastText(EmptySeats{4, 12, 2}, eta, ok)
+		if e != r {
+			t.Fatalf("wrong output, expected: %v, received: %v", e, r)
+		}
+	}
+
+	test(100*time.Minute, true, "Window: 4, Middle: 12, expected 0 in ~1h40m, Aisle: 2")
+	test(25*time.Minute, true, "Window: 4, Middle: 12, expected 0 in ~25m, Aisle: 2")
+	test(20*time.Second, true, "Window: 4, Middle: 12, expected 0 in <1m, Aisle: 2")
+	test(0, false, "Window: 4, Middle: 12, Aisle: 2")
+}
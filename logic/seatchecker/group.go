@@ -0,0 +1,52 @@
+package main
+
+import "fmt"
+
+// Availability of seats for passengers of a booking travelling together.
+type GroupFit struct {
+	Passengers  int  `json:"passengers"`
+	Rows        int  `json:"rows"`         // Rows with enough adjacent free seats for the whole group.
+	Pairs       int  `json:"pairs"`        // Rows with at least two adjacent free seats.
+	WindowAisle bool `json:"window_aisle"` // Enough free window and aisle seats for the whole group.
+}
+
+func (g GroupFit) together() bool {
+	return g.Rows > 0
+}
+
+func analyzeGroupFit(sm SeatMap, es EmptySeats, passengers int) GroupFit {
+	g := GroupFit{Passengers: passengers}
+	for r := 1; r <= sm.Rows; r++ {
+		fr := sm.freeRun(r)
+		if fr >= passengers {
+			g.Rows += 1
+		}
+		if fr >= 2 {
+			g.Pairs += 1
+		}
+	}
+	g.WindowAisle = es.Window+es.Aisle >= passengers
+	return g
+}
+
+func (g GroupFit) generateText() string {
+	return fmt.Sprintf("Group of %v: together in %v rows, %v pairs", g.Passengers, g.Rows, g.Pairs)
+}
+
+// Describe change of group fit, empty when nothing relevant for the group changed.
+func groupChangeText(p GroupFit, c GroupFit) string {
+	if p.Passengers != c.Passengers {
+		return ""
+	}
+	switch {
+	case p.together() && !c.together():
+		return fmt.Sprintf("Group of %v can no longer sit together", c.Passengers)
+	case !p.together() && c.together():
+		return fmt.Sprintf("Group of %v can sit together again", c.Passengers)
+	case p.WindowAisle && !c.WindowAisle:
+		return fmt.Sprintf("Not enough window and aisle seats for group of %v", c.Passengers)
+	case !p.WindowAisle && c.WindowAisle:
+		return fmt.Sprintf("Enough window and aisle seats for group of %v again", c.Passengers)
+	}
+	return ""
+}
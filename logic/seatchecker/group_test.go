@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestAnalyzeGroupFit(t *testing.T) {
+	us := []string{
+		"01A", "01B", "01C", "01D", "01E",
+		"02B", "02E",
+		"03A", "03B", "03F",
+	}
+	sm := newSeatMap(3, us)
+	es := calculateEmptySeats(3, us)
+
+	test := func(n int, e GroupFit) {
+		if r := analyzeGroupFit(sm, es, n); r != e {
+			t.Fatalf("wrong group fit for %v passengers, expected: %v, received: %v", n, e, r)
+		}
+	}
+
+	test(2, GroupFit{Passengers: 2, Rows: 2, Pairs: 2, WindowAisle: true})
+	test(3, GroupFit{Passengers: 3, Rows: 1, Pairs: 2, WindowAisle: true})
+	test(4, GroupFit{Passengers: 4, Rows: 0, Pairs: 2, WindowAisle: true})
+	test(8, GroupFit{Passengers: 8, Rows: 0, Pairs: 2, WindowAisle: false})
+}
+
+func TestGroupChangeText(t *testing.T) {
+	test := func(p GroupFit, c GroupFit, e string) {
+		if r := groupChangeText(p, c); r != e {
+			t.Fatalf("wrong output, expected: %v, received: %v", e, r)
+		}
+	}
+
+	together := GroupFit{Passengers: 3, Rows: 2, WindowAisle: true}
+	apart := GroupFit{Passengers: 3, Rows: 0, WindowAisle: true}
+	middle := GroupFit{Passengers: 3, Rows: 2, WindowAisle: false}
+
+	test(together, apart, "Group of 3 can no longer sit together")
+	test(apart, together, "Group of 3 can sit together again")
+	test(together, middle, "Not enough window and aisle seats for group of 3")
+	test(middle, together, "Enough window and aisle seats for group of 3 again")
+	test(together, GroupFit{Passengers: 3, Rows: 1, WindowAisle: true}, "")
+	// First run does not have previous state.
+	test(GroupFit{}, apart, "")
+}
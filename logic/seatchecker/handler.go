@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -15,13 +18,46 @@ import (
 )
 
 type Event struct {
-	RyanairEmail    string     `json:"ryanair_email"`
-	RyanairPassword string     `json:"ryanair_password"`
-	NtfyTopic       string     `json:"ntfy_topic"`
-	SeatState       EmptySeats `json:"seat_state"`
-	Status          int        `json:"status"`
-	Message         string     `json:"message"`
-	Departure       string     `json:"departure"`
+	Airline         string               `json:"airline"` // "ryanair" or "wizzair", defaults to Ryanair.
+	Email           string               `json:"email"`
+	Password        string               `json:"password"`
+	Pnr             string               `json:"pnr"`       // Looks up the booking instead of account login.
+	MfaToken        string               `json:"mfa_token"` // Challenge of the login, set with the code by /verify.
+	MfaCode         string               `json:"mfa_code"`
+	Device          string               `json:"device"`        // Fingerprint of the device logged in to the airline.
+	RyanairEmail    string               `json:"ryanair_email"` // Replaced by Email, kept for watches started before other airlines.
+	RyanairPassword string               `json:"ryanair_password"`
+	NtfyTopic       string               `json:"ntfy_topic"`
+	NtfyToken       string               `json:"ntfy_token"`
+	SeatState       EmptySeats           `json:"seat_state"`
+	Status          int                  `json:"status"`
+	Message         string               `json:"message"`
+	Departure       string               `json:"departure"`
+	Booking         string               `json:"booking"` // Booking of the tracked departure, fixed since the first run.
+	History         []Sample             `json:"history"`
+	ForecastModel   string               `json:"forecast_model"`
+	Allocation      []AllocationEstimate `json:"allocation"`
+	Group           GroupFit             `json:"group"`
+	NotifyOn        []string             `json:"notify_on"`
+	Zones           []Zone               `json:"zones"`
+	ZoneSeats       []ZoneSeats          `json:"zone_seats"`
+	ZoneText        bool                 `json:"zone_text"`
+	Preference      SeatPreference       `json:"preference"`
+	PreferredFree   []string             `json:"preferred_free"`
+	SeatMapText     bool                 `json:"seat_map_text"`
+	SeatMapImage    bool                 `json:"seat_map_image"`
+	Unavailable     []string             `json:"unavailable"`
+	DiffStyle       string               `json:"diff_style"`
+	TitleTemplate   string               `json:"title_template"`
+	BodyTemplate    string               `json:"body_template"`
+	Flight          FlightDetails        `json:"flight"`
+	Watch           Watch                `json:"watch"`
+	Policy          Policy               `json:"policy"`
+	Deliveries      map[string]Delivery  `json:"deliveries"`
+	Stats           WatchStats           `json:"stats"`
+	Aircraft        Aircraft             `json:"aircraft"`
+	Session         Session              `json:"session"`
+	Concurrency     int                  `json:"concurrency"` // Bookings fetched at once.
 }
 
 type EmptySeats struct {
@@ -35,30 +71,60 @@ func (es EmptySeats) generateText() string {
 	return fmt.Sprintf("Window: %v, Middle: %v, Aisle: %v", es.Window, es.Middle, es.Aisle)
 }
 
-// TODO: test this part of logic.
-func nextDeparture(js []string) (string, error) {
-	n := time.Now().UTC()
+// Account credentials, generic ones take precedence over the original Ryanair ones.
+func (e Event) credentials() (string, string) {
+	if e.Email != "" {
+		return e.Email, e.Password
+	}
+	return e.RyanairEmail, e.RyanairPassword
+}
 
-	// Find the upcoming flight.
-	var pt time.Time
-	var err error
-	for _, j := range js {
-		// RFC3339 is the formet Ryanair uses for time.
-		pt, err = time.Parse(time.RFC3339, j)
-		if err != nil {
-			return "", fmt.Errorf("error parsing time: %v", err)
-		}
-		if pt.Before(n) {
-			continue
-		} else {
-			break
-		}
+// Dependencies of the handler, replaced in tests.
+type Seatchecker struct {
+	clock     Clock
+	providers map[string]SeatProvider  // By airline.
+	seatMaps  map[string]*SeatMapCache // By airline, missing cache queries the seatmap every run.
+	ntfy      Client
+}
+
+func newSeatchecker() Seatchecker {
+	c := systemClock{}
+	hc := defaultHTTPClient()
+	dir := os.Getenv("SEATCHECKER_CACHE_DIR")
+	if dir == "" {
+		dir = os.TempDir() // The only writable directory in Lambda.
+	}
+	return Seatchecker{
+		clock: c,
+		providers: map[string]SeatProvider{
+			"ryanair": Ryanair{
+				mobile:  newClient("https", "services-api.ryanair.com", hc), // Ryanair Mobile API.
+				browser: newClient("https", "www.ryanair.com", hc),          // Ryanair Browser API.
+			},
+			"wizzair": Wizzair{
+				api: newClient("https", "be.wizzair.com", hc),
+			},
+		},
+		seatMaps: map[string]*SeatMapCache{
+			"ryanair": newSeatMapCache(c, filepath.Join(dir, "seatmaps_ryanair.json"), ryanairLayouts),
+			"wizzair": newSeatMapCache(c, filepath.Join(dir, "seatmaps_wizzair.json"), nil),
+		},
+		ntfy: newClient("https", "ntfy.sh", hc),
 	}
+}
 
-	return pt.UTC().String(), nil
+func (s Seatchecker) provider(airline string) (SeatProvider, *SeatMapCache, error) {
+	if airline == "" {
+		airline = "ryanair"
+	}
+	p, ok := s.providers[airline]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown airline: %v", airline)
+	}
+	return p, s.seatMaps[airline], nil
 }
 
-func handler(ctx context.Context, e Event) (Event, error) {
+func (s Seatchecker) handler(ctx context.Context, e Event) (Event, error) {
 	log.Println("Started Lambda execution.")
 
 	// Flush traces when handler finishes.
@@ -71,37 +137,210 @@ func handler(ctx context.Context, e Event) (Event, error) {
 		attribute.Int("middle", e.SeatState.Middle),
 		attribute.Int("aisle", e.SeatState.Aisle))
 
+	// Airline calls share the remaining time of the Lambda.
+	b := newBudget(ctx)
+	ctx = withBudget(ctx, b)
+	span.SetAttributes(attribute.String("budget", b.total.String()))
+
+	n := s.clock.Now().UTC()
+	// Subscribers are notified about failures once the watch is known to be valid.
+	valid := false
+
 	// Helper function to throw error.
 	throwErr := func(err error) (Event, error) {
 		span.RecordError(err, trace.WithStackTrace(true))
 		span.SetStatus(codes.Error, err.Error())
 		log.Printf("Error: %v\n", err)
+		// Step Function stops the watch on error.
+		if valid {
+			s.sendSummary(ctx, e, n, "failed")
+		}
 		// Returning nil error, as lambda finished.
 		// The error which happend in logic is returned through Message of Event response.
 		return Event{Status: 500, Message: err.Error()}, nil
 	}
+	// Helper function to reject invalid configuration of the watch.
+	throwInvalid := func(err error) (Event, error) {
+		r, _ := throwErr(err)
+		r.Status = 400
+		s.sendRejection(ctx, e, err)
+		return r, nil
+	}
 
-	// Ryanair Mobile API.
-	rmc := Client{scheme: "https", fqdn: "services-api.ryanair.com"}
+	p, sc, err := s.provider(e.Airline)
+	if err != nil {
+		return throwInvalid(err)
+	}
+	fm, err := forecastModel(e.ForecastModel)
+	if err != nil {
+		return throwInvalid(err)
+	}
+	if err := e.validateWatch(); err != nil {
+		return throwInvalid(err)
+	}
+	if err := validateZones(e.Zones); err != nil {
+		return throwInvalid(err)
+	}
+	if err := e.Preference.validate(); err != nil {
+		return throwInvalid(err)
+	}
+	if err := validateDiffStyle(e.DiffStyle); err != nil {
+		return throwInvalid(err)
+	}
+	if err := validateConcurrency(e.Concurrency); err != nil {
+		return throwInvalid(err)
+	}
+	nt, err := parseTemplates(e.TitleTemplate, e.BodyTemplate)
+	if err != nil {
+		return throwInvalid(err)
+	}
+	if err := e.validateVerification(); err != nil {
+		return throwInvalid(err)
+	}
+	var v Verifier
+	if e.MfaCode != "" {
+		var ok bool
+		if v, ok = p.(Verifier); !ok {
+			return throwInvalid(fmt.Errorf("airline does not support verification: %v", e.Airline))
+		}
+	}
+	var l BookingLookup
+	if e.Pnr != "" {
+		e.Pnr, err = normalizePnr(e.Pnr)
+		if err != nil {
+			return throwInvalid(err)
+		}
+		var ok bool
+		if l, ok = p.(BookingLookup); !ok {
+			return throwInvalid(fmt.Errorf("airline does not support booking lookup: %v", e.Airline))
+		}
+	}
+	valid = true
+	// Departure is tracked since the first run.
+	first := e.Departure == ""
 
-	log.Printf("Start Ryanair account login for user: %s.\n", e.RyanairEmail)
-	a, err := rmc.accountLogin(ctx, e.RyanairEmail, e.RyanairPassword)
+	var bs []Booking
+	var a Auth // Booking found by lookup does not need the account.
+	email, password := e.credentials()
+	if l != nil {
+		log.Printf("Look up booking %s of user: %s.\n", e.Pnr, email)
+		var ti TripInfo
+		ti, err = lookupTrip(ctx, l, e.Pnr, email)
+		bs = []Booking{{Trip: ti}}
+	} else {
+		log.Printf("Start account login for user: %s.\n", email)
+		if e.Device == "" {
+			// Generated on first login, the airline trusts it once verified.
+			if e.Device, err = newDevice(); err != nil {
+				return throwErr(err)
+			}
+		}
+		if v != nil {
+			log.Println("Verify the device.")
+			a, err = runStep(ctx, stepLogin, func(ctx context.Context) (Auth, error) {
+				return v.verify(ctx, email, e.Device, e.MfaToken, e.MfaCode)
+			})
+			// Device is trusted from now on.
+			e.MfaToken, e.MfaCode = "", ""
+		} else {
+			a, err = runStep(ctx, stepLogin, func(ctx context.Context) (Auth, error) {
+				return p.login(ctx, email, password, e.Device)
+			})
+		}
+		var ch *MfaChallenge
+		if errors.As(err, &ch) {
+			span.AddEvent("Account login requires verification.")
+			if err := s.requestVerification(ctx, e, email, ch); err != nil {
+				return throwErr(fmt.Errorf("failed to request verification: %v", err))
+			}
+			// Watch stops until the user resumes it through /verify.
+			return Event{Status: 401, Message: ch.Error(), Device: e.Device}, nil
+		}
+		if err != nil {
+			err := fmt.Errorf("login failed: %v", err)
+			return throwErr(err)
+		}
+		span.AddEvent("Account login finished successfully.")
+
+		log.Println("Query airline for bookings.")
+		bs, err = getTrips(ctx, p, a, e.Concurrency, e.Booking)
+	}
 	if err != nil {
-		err := fmt.Errorf("login failed: %v", err)
+		err := fmt.Errorf("failed to query airline for bookings, error: %v", err)
 		return throwErr(err)
 	}
-	span.AddEvent("Account login finished successfully.")
+	span.AddEvent("Bookings from airline retrieved successfully.")
 
-	// Ryanair Browser API.
-	rc := Client{scheme: "https", fqdn: "www.ryanair.com"}
+	// Keep track of the upcoming flight.
+	bk, dep, err := selectTrip(bs, e.Departure, n)
+	if err != nil {
+		err = fmt.Errorf("error calculating next departure: %v", err)
+		return throwErr(err)
+	}
+	e.Departure, e.Booking = dep, bk.Id
+	ti := bk.Trip
+	j := ti.journey(e.Departure)
+	// Flown journey disappeared from the booking, there are no seats to query anymore.
+	if j == (Journey{}) {
+		log.Println("Send summary of the watch.")
+		s.sendSummary(ctx, e, n, "finished")
+		e.SeatState = EmptySeats{0, 0, 0}
+		e.Status = 200
+		return e, nil
+	}
 
-	log.Println("Query Ryanair for seats.")
-	es, js, err := rc.getEmptySeats(ctx, a)
+	log.Println("Query airline for seats.")
+	f, err := tripSeats(ctx, p, sc, a, ti, j, e.Session)
 	if err != nil {
-		err := fmt.Errorf("failed to query ryanair for seats, error: %v", err)
+		err := fmt.Errorf("failed to query airline for seats, error: %v", err)
 		return throwErr(err)
 	}
-	span.AddEvent("Seats from Ryanair retrieved successfully.")
+	span.AddEvent("Seats from airline retrieved successfully.")
+	es := f.Empty
+	e.Session = f.Session
+	e.Flight = flightDetails(j, ti.Info.Pnr)
+
+	prev := e.Aircraft
+	e.Aircraft = Aircraft{Model: f.Info.EquipmentModel, Rows: f.Rows}
+	swapped := aircraftChanged(prev, e.Aircraft)
+	if swapped {
+		log.Printf("Aircraft changed from %v to %v.\n", prev.Model, e.Aircraft.Model)
+		span.AddEvent("Aircraft changed.", trace.WithAttributes(
+			attribute.String("previous", prev.Model),
+			attribute.String("current", e.Aircraft.Model)))
+		s.sendAircraftChange(ctx, e, prev, es)
+		e.resetBaseline(es)
+	}
+
+	e.History = recordSample(e.History, n, es)
+	eta, ok := forecastDepletion(e.History, middleSeats, fm, n)
+	if ok {
+		span.AddEvent("Middle seats depletion forecasted.", trace.WithAttributes(
+			attribute.String("eta", eta.String())))
+	}
+
+	ps := seatedPassengers(f.Trip.Passengers)
+	e.Allocation = estimateAllocation(es, ps)
+
+	sm := newSeatMap(f.Rows, f.Info.UnavailableSeats)
+	g := analyzeGroupFit(sm, es, ps)
+	gTxt := groupChangeText(e.Group, g)
+	e.Group = g
+
+	e.ZoneSeats = calculateZoneSeats(sm, f.Info.EquipmentModel, e.Zones)
+
+	var prefTxt string
+	if e.Preference.active() {
+		pf := e.Preference.freeSeats(sm, f.Info.EquipmentModel)
+		prefTxt = preferenceChangeText(e.PreferredFree, pf)
+		e.PreferredFree = pf
+	}
+
+	var sd SeatDiff
+	if !first && !swapped {
+		sd = diffSeats(e.Unavailable, f.Info.UnavailableSeats)
+	}
+	e.Unavailable = f.Info.UnavailableSeats
 
 	pTxt := e.SeatState.generateText()
 	log.Printf("Previous execution: %v", pTxt)
@@ -113,36 +352,125 @@ func handler(ctx context.Context, e Event) (Event, error) {
 	span.AddEvent("Current execution text generated.", trace.WithAttributes(
 		attribute.String("current_execution", cTxt)))
 
+	var triggered []string
 	if pTxt != cTxt {
+		triggered = append(triggered, conditionSeats)
+	}
+	if gTxt != "" {
+		triggered = append(triggered, conditionGroup)
+	}
+	if prefTxt != "" {
+		triggered = append(triggered, conditionPreference)
+	}
+
+	var rs []Subscriber
+	for _, sub := range e.subscribers() {
+		d := e.Deliveries[sub.key()]
+		ts := triggered
+		if sub.Policy.active() && d.pending(es) && !slices.Contains(ts, conditionSeats) {
+			ts = append(slices.Clone(ts), conditionSeats)
+		}
+		if !sub.wants(e, ts, es) {
+			continue
+		}
+		if ok, why := sub.Policy.allows(d, n, es, ts); !ok {
+			log.Printf("Notification for subscriber %v suppressed: %v.\n", sub.Name, why)
+			span.AddEvent("Notification suppressed.", trace.WithAttributes(
+				attribute.String("subscriber", sub.Name),
+				attribute.String("reason", why)))
+			continue
+		}
+		rs = append(rs, sub)
+	}
+
+	if len(rs) > 0 {
 		// Send notification that there is a change in seat availability.
-		nc := Client{scheme: "https", fqdn: "ntfy.sh"}
+		ls := []string{forecastText(es, eta, ok)}
+		if fTxt := e.Flight.generateText(); fTxt != "" {
+			ls[0] = fTxt + " — " + ls[0]
+		}
+		if !sd.empty() {
+			ls = append(ls, sd.generateText(e.DiffStyle))
+		}
+		if gTxt != "" {
+			ls = append(ls, gTxt)
+		} else if g.Passengers > 1 {
+			ls = append(ls, g.generateText())
+		}
+		if len(e.Allocation) > 0 {
+			ls = append(ls, allocationText(e.Allocation))
+		}
+		if e.ZoneText {
+			ls = append(ls, zoneText(e.ZoneSeats))
+		}
+		if prefTxt != "" {
+			ls = append(ls, prefTxt)
+		} else if e.Preference.active() {
+			ls = append(ls, preferenceText(e.PreferredFree))
+		}
 
-		log.Println("Send notification.")
-		err := nc.sendNotification(ctx, e.NtfyTopic, cTxt)
+		if e.SeatMapText {
+			ls = append(ls, renderASCII(sm))
+		}
+		nd := NotificationData{
+			Seats:     es,
+			Previous:  e.SeatState,
+			Diff:      sd,
+			Departure: e.Departure,
+			Flight:    e.Flight,
+			Text:      strings.Join(ls, "\n"),
+		}
+		if ok {
+			nd.Forecast = formatETA(eta)
+		}
+		title, txt, err := nt.render(nd)
 		if err != nil {
-			err = fmt.Errorf("failed to send notification, error: %v", err)
-			return throwErr(err)
+			// Template valid for the sample data may still fail on this run, e.g. index into empty diff.
+			span.RecordError(err)
+			log.Printf("Error: %v, default notification is sent\n", err)
+			title, txt = defaultTitle, nd.Text
 		}
-		span.AddEvent("Notification sent successfully.")
-	}
 
-	// Execute on first run.
-	if e.Departure == "" {
-		e.Departure, err = nextDeparture(js)
+		m := Message{Title: title, Text: txt}
+		if e.SeatMapImage {
+			m.Filename = "seatmap.png"
+			m.Attachment, err = renderPNG(sm)
+			if err != nil {
+				err = fmt.Errorf("failed to render seat map, error: %v", err)
+				return throwErr(err)
+			}
+		}
+
+		sent, err := s.fanOut(ctx, rs, m)
 		if err != nil {
-			err = fmt.Errorf("error calculating next departure: %v", err)
-			return throwErr(err)
+			err = fmt.Errorf("failed to send notification, error: %v", err)
+			// Watch continues while at least one subscriber is notified.
+			if len(sent) == 0 {
+				return throwErr(err)
+			}
+			span.RecordError(err)
+			log.Printf("Error: %v\n", err)
+		}
+		if e.Deliveries == nil {
+			e.Deliveries = map[string]Delivery{}
+		}
+		for _, sub := range sent {
+			e.Deliveries[sub.key()] = Delivery{Time: n.Format(time.RFC3339), Seats: es}
 		}
+		span.AddEvent("Notification sent successfully.", trace.WithAttributes(
+			attribute.Int("subscribers", len(sent))))
 	}
 
 	// Execute on last run.
-	d, err := time.Parse(time.RFC3339, e.Departure)
+	d, err := parseDeparture(e.Departure)
 	if err != nil {
-		err = fmt.Errorf("error parsing time: %v", err)
 		return throwErr(err)
 	}
-	// The flight has departed.
-	if time.Now().UTC().After(d) {
+	e.Stats = e.Stats.record(n, es)
+	// The flight has departed or sold out, Step Function ends the watch without empty seats.
+	if n.After(d) || es == (EmptySeats{0, 0, 0}) {
+		log.Println("Send summary of the watch.")
+		s.sendSummary(ctx, e, n, "finished")
 		es = EmptySeats{0, 0, 0}
 	}
 
@@ -159,9 +487,11 @@ func main() {
 
 	defer setupOtel(ctx)()
 
+	s := newSeatchecker()
+
 	if strings.HasPrefix(os.Getenv("AWS_EXECUTION_ENV"), "AWS_Lambda_") {
 		log.Println("Running in AWS Lambda.")
-		lambda.Start(handler)
+		lambda.Start(s.handler)
 	} else {
 		log.Println("Running locally.")
 		i := Event{
@@ -174,7 +504,10 @@ func main() {
 				Aisle:  99,
 			},
 		}
-		resp, _ := handler(ctx, i)
+		resp, _ := s.handler(ctx, i)
 		log.Println(resp)
+		if resp.Status == 200 {
+			log.Printf("Seat map:\n%v", renderASCII(newSeatMap(resp.Aircraft.Rows, resp.Unavailable)))
+		}
 	}
 }
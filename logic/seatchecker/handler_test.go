@@ -1,6 +1,18 @@
 package main
 
-import "testing"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestGenerateText(t *testing.T) {
 	e := "Window: 4, Middle: 0, Aisle: 2"
@@ -9,3 +21,764 @@ func TestGenerateText(t *testing.T) {
 		t.Fatalf("wrong output, expected: %v, received: %v", e, r)
 	}
 }
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// Stand-in for both Ryanair APIs, serving a booking of a plane with 4 rows.
+type stubRyanair struct {
+	departures  []string
+	passengers  []Passenger
+	unavailable []string
+	mfa         bool             // Device has to be verified before login.
+	trusted     string           // Fingerprint of the verified device.
+	model       string           // Equipment model, defaults to "32A".
+	baskets     int              // Number of created baskets.
+	others      []string         // Departures of other bookings of the account, one journey each.
+	byJourney   map[int][]string // Unavailable seats of the journey, overrides unavailable.
+	hang        string           // Path of the endpoint which responds only after the client gives up.
+	broken      string           // Booking which fails to be fetched.
+}
+
+func (sr *stubRyanair) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if sr.hang != "" && strings.Contains(r.URL.Path, sr.hang) {
+		// Disconnect of the client is noticed only after the body is read.
+		io.ReadAll(r.Body)
+		<-r.Context().Done()
+		return
+	}
+
+	var res any
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/accountLogin"):
+		if sr.mfa && r.Header.Get("X-Fingerprint") != sr.trusted {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, `{"code": "Account.UnknownDeviceFingerprint", "additionalData": [{"code": "mfaToken", "message": "mfa_token"}]}`)
+			return
+		}
+		res = Auth{"customerid", "token"}
+	case strings.HasSuffix(r.URL.Path, "/deviceFingerprint"):
+		sr.trusted = r.Header.Get("X-Fingerprint")
+		res = Auth{"customerid", "token"}
+	case strings.Contains(r.URL.Path, "/orders/"):
+		fs := []BIdFlight{{BookingId: "booking_id"}}
+		for i := range sr.others {
+			fs = append(fs, BIdFlight{BookingId: fmt.Sprintf("other_%v", i)})
+		}
+		res = BIdResp{Items: []BIdItem{{Flights: fs}}}
+	case strings.Contains(r.URL.Path, "/bookingfa/"):
+		b, _ := io.ReadAll(r.Body)
+		q := GqlQuery[TIVars]{}
+		json.Unmarshal(b, &q)
+		if sr.broken != "" && q.Variables.BookingInfo.BookingId == sr.broken {
+			http.Error(w, "{}", http.StatusInternalServerError)
+			return
+		}
+		ds, id := sr.departures, "trip_id"
+		var i int
+		if _, err := fmt.Sscanf(q.Variables.BookingInfo.BookingId, "other_%d", &i); err == nil {
+			ds, id = sr.others[i:i+1], q.Variables.BookingInfo.BookingId
+		}
+		var js []Journey
+		for i, d := range ds {
+			js = append(js, Journey{
+				JourneyNum:   i,
+				DepartUTC:    d,
+				Depart:       strings.TrimSuffix(d, "Z") + ".000",
+				Orig:         "DUB",
+				Dest:         "STN",
+				FlightNumber: "FR 1234",
+			})
+		}
+		ti := TripInfo{
+			TripId:       id,
+			SessionToken: "session_token",
+			Journeys:     js,
+			Passengers:   sr.passengers,
+			Info:         BookingInfo{Pnr: "ABC123"},
+		}
+		// Manage booking flow looks up the booking by reservation number.
+		if strings.Contains(string(b), "getBookingByReservationNumber") {
+			res = GqlResponse[RNData]{Data: RNData{TI: ti}}
+			break
+		}
+		res = GqlResponse[TIData]{Data: TIData{TI: ti}}
+	case strings.Contains(r.URL.Path, "/basketapi/"):
+		sr.baskets += 1
+		res = GqlResponse[BData]{Data: BData{Basket: Basket{Id: "basket_id"}}}
+	case strings.Contains(r.URL.Path, "/catalogapi/"):
+		// Basket contains seats of every journey of the trip.
+		var fis []FlightInfo
+		for i := range max(len(sr.departures), 1) {
+			fi := FlightInfo{JourneyNum: i, UnavailableSeats: sr.unavailable, EquipmentModel: "32A"}
+			if us, ok := sr.byJourney[i]; ok {
+				fi.UnavailableSeats = us
+			}
+			if sr.model != "" {
+				fi.EquipmentModel = sr.model
+			}
+			fis = append(fis, fi)
+		}
+		res = GqlResponse[FIData]{Data: FIData{FlightInfos: fis}}
+	case strings.HasSuffix(r.URL.Path, "/seatmap"):
+		res = []NORResp{{SeatRows: [][]NORSeat{{{Row: 1}}, {{Row: 2}}, {{Row: 3}}, {{Row: 4}}}}}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	b, _ := json.Marshal(res)
+	fmt.Fprintln(w, string(b))
+}
+
+// Stand-in for ntfy.sh, recording received notifications.
+type stubNtfy struct {
+	notifications []Notification
+}
+
+func (sn *stubNtfy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rawB, _ := io.ReadAll(r.Body)
+	n := Notification{}
+	json.Unmarshal(rawB, &n)
+	sn.notifications = append(sn.notifications, n)
+	fmt.Fprintln(w, "{}")
+}
+
+func newTestSeatchecker(t *testing.T, c Clock, sr *stubRyanair, sn *stubNtfy) Seatchecker {
+	rs := httptest.NewServer(sr)
+	t.Cleanup(rs.Close)
+	ns := httptest.NewServer(sn)
+	t.Cleanup(ns.Close)
+
+	rc := Client{scheme: "http", fqdn: rs.URL}
+	return Seatchecker{
+		clock:     c,
+		providers: map[string]SeatProvider{"ryanair": Ryanair{mobile: rc, browser: rc}},
+		ntfy:      Client{scheme: "http", fqdn: ns.URL},
+	}
+}
+
+func TestHandlerLifecycle(t *testing.T) {
+	c := &fakeClock{time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)}
+	sr := &stubRyanair{
+		departures:  []string{"2024-06-28T08:00:00Z", "2024-07-05T18:30:00Z"},
+		passengers:  []Passenger{{1, "ADT"}, {2, "INF"}},
+		unavailable: []string{"01A", "01B", "02B", "03E"},
+	}
+	sn := &stubNtfy{}
+	s := newTestSeatchecker(t, c, sr, sn)
+
+	run := func(e Event) Event {
+		r, err := s.handler(context.Background(), e)
+		if err != nil {
+			t.Fatalf("handler failed: %v", err)
+		}
+		if r.Status != 200 {
+			t.Fatalf("wrong status, expected: 200, received: %v, message: %v", r.Status, r.Message)
+		}
+		return r
+	}
+
+	// First run.
+	e := run(Event{NtfyTopic: "topic", SeatState: EmptySeats{99, 99, 99}})
+	if e.Departure != "2024-07-05T18:30:00Z" {
+		t.Fatalf("wrong departure, expected: 2024-07-05T18:30:00Z, received: %v", e.Departure)
+	}
+	ef := FlightDetails{"FR 1234", "DUB", "STN", "2024-07-05T18:30:00.000", "", "ABC123"}
+	if e.Flight != ef {
+		t.Fatalf("wrong flight, expected: %v, received: %v", ef, e.Flight)
+	}
+	if es := (EmptySeats{7, 5, 8}); e.SeatState != es {
+		t.Fatalf("wrong seat state, expected: %v, received: %v", es, e.SeatState)
+	}
+	if len(sn.notifications) != 1 {
+		t.Fatalf("wrong number of notifications, expected: 1, received: %v", len(sn.notifications))
+	}
+	em := "FR1234 DUB→STN 18:30 — Window: 7, Middle: 5, Aisle: 8\nWindow/Aisle chance: 75% uniform, 0% middle-first"
+	if m := sn.notifications[0].Message; m != em {
+		t.Fatalf("wrong notification, expected: %v, received: %v", em, m)
+	}
+	if len(e.Allocation) != 2 {
+		t.Fatalf("wrong number of allocation estimates, expected: 2, received: %v", len(e.Allocation))
+	}
+	if zs := e.ZoneSeats[0]; zs.Name != "front" || zs.Seats != e.SeatState {
+		t.Fatalf("wrong seats in front zone, received: %v", zs)
+	}
+
+	// Mid-watch without changes in seat availability.
+	c.now = c.now.Add(10 * time.Minute)
+	e = run(e)
+	if len(sn.notifications) != 1 {
+		t.Fatalf("notification sent without change in seats, received: %v", sn.notifications)
+	}
+
+	// Mid-watch with more seats taken.
+	sr.unavailable = append(sr.unavailable, "04B", "04E")
+	c.now = c.now.Add(10 * time.Minute)
+	e = run(e)
+	if len(sn.notifications) != 2 {
+		t.Fatalf("wrong number of notifications, expected: 2, received: %v", len(sn.notifications))
+	}
+	if es := (EmptySeats{7, 3, 8}); e.SeatState != es {
+		t.Fatalf("wrong seat state, expected: %v, received: %v", es, e.SeatState)
+	}
+	em = "FR1234 DUB→STN 18:30 — Window: 7, Middle: 3, expected 0 in ~33m, Aisle: 8\n" +
+		"+2 taken: 2 middle; newly taken 04B, 04E\n" +
+		"Window/Aisle chance: 83% uniform, 0% middle-first"
+	if m := sn.notifications[1].Message; m != em {
+		t.Fatalf("wrong notification, expected: %v, received: %v", em, m)
+	}
+	if len(e.History) != 3 {
+		t.Fatalf("wrong history length, expected: 3, received: %v", len(e.History))
+	}
+	// Basket of the first run is reused.
+	if sr.baskets != 1 || e.Session.BasketId != "basket_id" {
+		t.Fatalf("basket not reused, created: %v, session: %v", sr.baskets, e.Session)
+	}
+
+	// After departure.
+	c.now = time.Date(2024, 7, 5, 18, 31, 0, 0, time.UTC)
+	e = run(e)
+	if es := (EmptySeats{0, 0, 0}); e.SeatState != es {
+		t.Fatalf("seat state not reset after departure, received: %v", e.SeatState)
+	}
+	if e.Departure != "2024-07-05T18:30:00Z" {
+		t.Fatalf("tracked departure changed, received: %v", e.Departure)
+	}
+	if len(sn.notifications) != 3 {
+		t.Fatalf("wrong number of notifications, expected: 3, received: %v", len(sn.notifications))
+	}
+	if n := sn.notifications[2]; n.Title != "Seatchecker: watch finished" || !strings.Contains(n.Message, "4 polls") {
+		t.Fatalf("wrong summary, received: %v", n)
+	}
+}
+
+func TestHandlerFailure(t *testing.T) {
+	c := &fakeClock{time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)}
+	// All journeys of the booking already departed.
+	sr := &stubRyanair{departures: []string{"2024-06-28T08:00:00Z"}}
+	sn := &stubNtfy{}
+	s := newTestSeatchecker(t, c, sr, sn)
+
+	r, err := s.handler(context.Background(), Event{NtfyTopic: "topic"})
+	if err != nil {
+		t.Fatalf("handler should report failures through event, received: %v", err)
+	}
+	if r.Status != 500 {
+		t.Fatalf("wrong status, expected: 500, received: %v", r.Status)
+	}
+	if !strings.Contains(r.Message, errNoUpcomingJourney.Error()) {
+		t.Fatalf("wrong message, received: %v", r.Message)
+	}
+	if len(sn.notifications) != 1 || sn.notifications[0].Title != "Seatchecker: watch failed" {
+		t.Fatalf("subscriber not notified about failure, received: %v", sn.notifications)
+	}
+}
+
+func TestHandlerGroupCondition(t *testing.T) {
+	c := &fakeClock{time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)}
+	sr := &stubRyanair{
+		departures:  []string{"2024-07-05T18:30:00Z"},
+		passengers:  []Passenger{{1, "ADT"}, {2, "ADT"}},
+		unavailable: []string{"01A", "01C", "01E", "02B", "02D", "02F", "03A", "03C", "03E"},
+	}
+	sn := &stubNtfy{}
+	s := newTestSeatchecker(t, c, sr, sn)
+
+	e, _ := s.handler(context.Background(), Event{NtfyTopic: "topic", NotifyOn: []string{"group"}})
+	if e.Group.Rows != 1 {
+		t.Fatalf("wrong number of rows for group, expected: 1, received: %v", e.Group.Rows)
+	}
+	if len(sn.notifications) != 0 {
+		t.Fatalf("notification sent without change of group fit, received: %v", sn.notifications)
+	}
+
+	// Seats are taken, but group can still sit together.
+	sr.unavailable = append(sr.unavailable, "04B", "04D")
+	e, _ = s.handler(context.Background(), e)
+	if len(sn.notifications) != 0 {
+		t.Fatalf("notification sent without change of group fit, received: %v", sn.notifications)
+	}
+
+	sr.unavailable = append(sr.unavailable, "04E")
+	e, _ = s.handler(context.Background(), e)
+	if len(sn.notifications) != 1 {
+		t.Fatalf("wrong number of notifications, expected: 1, received: %v", len(sn.notifications))
+	}
+	if m := sn.notifications[0].Message; !strings.Contains(m, "Group of 2 can no longer sit together") {
+		t.Fatalf("wrong notification, received: %v", m)
+	}
+
+	e, _ = s.handler(context.Background(), Event{NotifyOn: []string{"sometimes"}})
+	if e.Status != 400 {
+		t.Fatalf("unknown condition accepted, received status: %v", e.Status)
+	}
+}
+
+func TestHandlerReturnJourney(t *testing.T) {
+	c := &fakeClock{time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)}
+	sr := &stubRyanair{
+		departures: []string{"2024-06-28T08:00:00Z", "2024-07-05T18:30:00Z"},
+		byJourney: map[int][]string{
+			0: {"01A", "01B", "01C", "01D", "01E", "01F", "02A"},
+			1: {"01A"},
+		},
+	}
+	sn := &stubNtfy{}
+	s := newTestSeatchecker(t, c, sr, sn)
+
+	// Outbound journey departed, seats of the return one are tracked.
+	e, _ := s.handler(context.Background(), Event{NtfyTopic: "topic"})
+	if e.Status != 200 {
+		t.Fatalf("wrong status, expected: 200, received: %v, message: %v", e.Status, e.Message)
+	}
+	if e.Departure != "2024-07-05T18:30:00Z" || e.Flight.Departure != "2024-07-05T18:30:00.000" {
+		t.Fatalf("wrong tracked journey: %v, %+v", e.Departure, e.Flight)
+	}
+	if es := (EmptySeats{7, 8, 8}); e.SeatState != es || !reflect.DeepEqual(e.Unavailable, []string{"01A"}) {
+		t.Fatalf("seats of other journey, expected: %v, received: %v, %v", es, e.SeatState, e.Unavailable)
+	}
+
+	// Flown return journey disappeared from the booking.
+	c.now = time.Date(2024, 7, 5, 19, 0, 0, 0, time.UTC)
+	sr.departures = sr.departures[:1]
+	e, _ = s.handler(context.Background(), e)
+	if e.Status != 200 || e.SeatState != (EmptySeats{0, 0, 0}) {
+		t.Fatalf("watch not finished, status: %v, seats: %v, message: %v", e.Status, e.SeatState, e.Message)
+	}
+	if n := sn.notifications[len(sn.notifications)-1]; n.Title != "Seatchecker: watch finished" {
+		t.Fatalf("summary not sent, received: %v", n)
+	}
+}
+
+func TestHandlerSoldOut(t *testing.T) {
+	c := &fakeClock{time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)}
+	sr := &stubRyanair{departures: []string{"2024-07-05T18:30:00Z"}}
+	for _, r := range []string{"01", "02", "03", "04"} {
+		for _, col := range seatColumns {
+			sr.unavailable = append(sr.unavailable, r+col)
+		}
+	}
+	sn := &stubNtfy{}
+	s := newTestSeatchecker(t, c, sr, sn)
+
+	// Step Function ends the watch on zero seats, before the departure.
+	e, _ := s.handler(context.Background(), Event{NtfyTopic: "topic", SeatState: EmptySeats{1, 1, 1}})
+	if e.Status != 200 || e.SeatState != (EmptySeats{0, 0, 0}) {
+		t.Fatalf("wrong state, status: %v, seats: %v, message: %v", e.Status, e.SeatState, e.Message)
+	}
+	if n := sn.notifications[len(sn.notifications)-1]; n.Title != "Seatchecker: watch finished" {
+		t.Fatalf("summary not sent, received: %v", n)
+	}
+}
+
+func TestHandlerBookings(t *testing.T) {
+	c := &fakeClock{time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)}
+	sr := &stubRyanair{
+		departures:  []string{"2024-07-05T18:30:00Z"},
+		others:      []string{"2024-07-20T08:00:00Z", "2024-08-01T08:00:00Z"},
+		unavailable: []string{"01A"},
+	}
+	sn := &stubNtfy{}
+	s := newTestSeatchecker(t, c, sr, sn)
+
+	e := Event{NtfyTopic: "topic", Concurrency: 2}
+	for range 3 {
+		e, _ = s.handler(context.Background(), e)
+		if e.Status != 200 {
+			t.Fatalf("wrong status, expected: 200, received: %v, message: %v", e.Status, e.Message)
+		}
+		c.now = c.now.Add(10 * time.Minute)
+	}
+	// Seats are queried only for the booking of the tracked departure.
+	if e.Departure != "2024-07-05T18:30:00Z" || e.Session.TripId != "trip_id" {
+		t.Fatalf("wrong tracked booking: %v, %v", e.Departure, e.Session)
+	}
+	if sr.baskets != 1 {
+		t.Fatalf("baskets created for untracked bookings, created: %v", sr.baskets)
+	}
+}
+
+func TestHandlerTrackedBookingFails(t *testing.T) {
+	c := &fakeClock{time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)}
+	sr := &stubRyanair{
+		departures:  []string{"2024-07-05T18:30:00Z"},
+		others:      []string{"2024-07-20T08:00:00Z"},
+		unavailable: []string{"01A"},
+	}
+	sn := &stubNtfy{}
+	s := newTestSeatchecker(t, c, sr, sn)
+
+	e, _ := s.handler(context.Background(), Event{NtfyTopic: "topic"})
+	if e.Status != 200 || e.Booking != "booking_id" {
+		t.Fatalf("wrong tracked booking, status: %v, booking: %v, message: %v", e.Status, e.Booking, e.Message)
+	}
+
+	// Watch does not move to the other booking.
+	sr.broken = "booking_id"
+	c.now = c.now.Add(10 * time.Minute)
+	r, _ := s.handler(context.Background(), e)
+	if r.Status != 500 || !strings.Contains(r.Message, "booking_id") {
+		t.Fatalf("expected failure of the tracked booking, status: %v, message: %v", r.Status, r.Message)
+	}
+}
+
+func TestHandlerPreference(t *testing.T) {
+	c := &fakeClock{time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)}
+	sr := &stubRyanair{
+		departures:  []string{"2024-07-05T18:30:00Z"},
+		unavailable: []string{"01A"},
+	}
+	sn := &stubNtfy{}
+	s := newTestSeatchecker(t, c, sr, sn)
+
+	p := SeatPreference{Seats: []string{"2A", "2F", "3A"}}
+	e, _ := s.handler(context.Background(), Event{NtfyTopic: "topic", NotifyOn: []string{"preference"}, Preference: p})
+	if !reflect.DeepEqual(e.PreferredFree, []string{"02A", "02F", "03A"}) {
+		t.Fatalf("wrong preferred free seats, received: %v", e.PreferredFree)
+	}
+	if len(sn.notifications) != 0 {
+		t.Fatalf("notification sent without preferred seats taken, received: %v", sn.notifications)
+	}
+
+	sr.unavailable = append(sr.unavailable, "02A", "03A")
+	e, _ = s.handler(context.Background(), e)
+	if len(sn.notifications) != 1 {
+		t.Fatalf("wrong number of notifications, expected: 1, received: %v", len(sn.notifications))
+	}
+	if m := sn.notifications[0].Message; !strings.Contains(m, "Last preferred seat remaining: 02F") {
+		t.Fatalf("wrong notification, received: %v", m)
+	}
+
+	e, _ = s.handler(context.Background(), Event{Preference: SeatPreference{Seats: []string{"A17"}}})
+	if e.Status != 400 {
+		t.Fatalf("invalid preference accepted, received status: %v", e.Status)
+	}
+}
+
+func TestHandlerTemplates(t *testing.T) {
+	c := &fakeClock{time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)}
+	sr := &stubRyanair{
+		departures:  []string{"2024-07-05T18:30:00Z"},
+		unavailable: []string{"01A"},
+	}
+	sn := &stubNtfy{}
+	s := newTestSeatchecker(t, c, sr, sn)
+
+	e := Event{
+		NtfyTopic:     "topic",
+		TitleTemplate: "Flight {{.Departure}}",
+		BodyTemplate:  "Middle: {{.Previous.Middle}} -> {{.Seats.Middle}}",
+		SeatState:     EmptySeats{99, 99, 99},
+	}
+	e, _ = s.handler(context.Background(), e)
+	if e.Status != 200 {
+		t.Fatalf("wrong status, expected: 200, received: %v, message: %v", e.Status, e.Message)
+	}
+	if n := sn.notifications[0]; n.Title != "Flight 2024-07-05T18:30:00Z" || n.Message != "Middle: 99 -> 8" {
+		t.Fatalf("wrong notification, received: %v", n)
+	}
+
+	// Template failing on data of the run falls back to the default notification.
+	sr.unavailable = append(sr.unavailable, "02B")
+	e.BodyTemplate = "Released {{index .Diff.Released 0}}"
+	e, _ = s.handler(context.Background(), e)
+	if e.Status != 200 {
+		t.Fatalf("wrong status, expected: 200, received: %v, message: %v", e.Status, e.Message)
+	}
+	if n := sn.notifications[1]; n.Title != defaultTitle || !strings.Contains(n.Message, "newly taken 02B") {
+		t.Fatalf("wrong notification, received: %v", n)
+	}
+
+	// Invalid templates are rejected before querying Ryanair, the topic learns why.
+	e, _ = s.handler(context.Background(), Event{NtfyTopic: "topic", BodyTemplate: "{{.Seats.Cockpit}}"})
+	if e.Status != 400 {
+		t.Fatalf("invalid template accepted, received status: %v", e.Status)
+	}
+	if n := sn.notifications[2]; n.Title != "Seatchecker: watch rejected" || !strings.Contains(n.Message, "Cockpit") {
+		t.Fatalf("wrong rejection, received: %v", n)
+	}
+}
+
+func TestHandlerSubscribers(t *testing.T) {
+	c := &fakeClock{time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)}
+	sr := &stubRyanair{
+		departures:  []string{"2024-07-05T18:30:00Z"},
+		unavailable: []string{"01A", "01B", "02B", "03E"},
+	}
+	sn := &stubNtfy{}
+	s := newTestSeatchecker(t, c, sr, sn)
+
+	w := Watch{
+		Owner: "john@doe.com",
+		Subscribers: []Subscriber{
+			{Name: "john", NtfyTopic: "john"},
+			{Name: "jane", NtfyTopic: "jane", MiddleBelow: 4},
+		},
+	}
+	e, _ := s.handler(context.Background(), Event{Watch: w, SeatState: EmptySeats{99, 99, 99}})
+	if len(sn.notifications) != 1 || sn.notifications[0].Topic != "john" {
+		t.Fatalf("wrong notifications, received: %v", sn.notifications)
+	}
+
+	sr.unavailable = append(sr.unavailable, "04B", "04E")
+	e, _ = s.handler(context.Background(), e)
+	if len(sn.notifications) != 3 || sn.notifications[2].Topic != "jane" {
+		t.Fatalf("wrong notifications, received: %v", sn.notifications)
+	}
+}
+
+func TestHandlerPolicyFirstRun(t *testing.T) {
+	c := &fakeClock{time.Date(2024, 7, 1, 23, 0, 0, 0, time.UTC)}
+	sr := &stubRyanair{
+		departures:  []string{"2024-07-05T18:30:00Z"},
+		unavailable: []string{"01A"},
+	}
+	sn := &stubNtfy{}
+	s := newTestSeatchecker(t, c, sr, sn)
+
+	// Watch started during quiet hours.
+	p := Policy{QuietHours: QuietHours{"22:00", "07:00"}}
+	e, _ := s.handler(context.Background(), Event{NtfyTopic: "topic", Policy: p})
+	if len(sn.notifications) != 0 {
+		t.Fatalf("notification sent during quiet hours, received: %v", sn.notifications)
+	}
+
+	// First notification is delivered after quiet hours, although seats did not change.
+	c.now = time.Date(2024, 7, 2, 7, 0, 0, 0, time.UTC)
+	e, _ = s.handler(context.Background(), e)
+	if len(sn.notifications) != 1 {
+		t.Fatalf("wrong number of notifications, expected: 1, received: %v", len(sn.notifications))
+	}
+	if d := e.Deliveries["/topic"]; d.Time != "2024-07-02T07:00:00Z" {
+		t.Fatalf("wrong delivery state, received: %v", d)
+	}
+}
+
+func TestHandlerPolicy(t *testing.T) {
+	c := &fakeClock{time.Date(2024, 7, 1, 21, 50, 0, 0, time.UTC)}
+	sr := &stubRyanair{
+		departures:  []string{"2024-07-05T18:30:00Z"},
+		unavailable: []string{"01A"},
+	}
+	sn := &stubNtfy{}
+	s := newTestSeatchecker(t, c, sr, sn)
+
+	p := Policy{QuietHours: QuietHours{"22:00", "07:00"}}
+	e, _ := s.handler(context.Background(), Event{NtfyTopic: "topic", Policy: p})
+	if len(sn.notifications) != 1 {
+		t.Fatalf("wrong number of notifications, expected: 1, received: %v", len(sn.notifications))
+	}
+
+	// Seats taken during quiet hours.
+	sr.unavailable = append(sr.unavailable, "02B")
+	c.now = time.Date(2024, 7, 1, 22, 0, 0, 0, time.UTC)
+	e, _ = s.handler(context.Background(), e)
+	c.now = time.Date(2024, 7, 2, 6, 50, 0, 0, time.UTC)
+	e, _ = s.handler(context.Background(), e)
+	if len(sn.notifications) != 1 {
+		t.Fatalf("notification sent during quiet hours, received: %v", sn.notifications)
+	}
+
+	// Missed change is delivered after quiet hours.
+	c.now = time.Date(2024, 7, 2, 7, 0, 0, 0, time.UTC)
+	e, _ = s.handler(context.Background(), e)
+	if len(sn.notifications) != 2 {
+		t.Fatalf("wrong number of notifications, expected: 2, received: %v", len(sn.notifications))
+	}
+	if d := e.Deliveries["/topic"]; d.Time != "2024-07-02T07:00:00Z" || d.Seats != e.SeatState {
+		t.Fatalf("wrong delivery state, received: %v", d)
+	}
+}
+
+func TestHandlerAirline(t *testing.T) {
+	c := &fakeClock{time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)}
+	sr := &stubRyanair{
+		departures:  []string{"2024-07-05T18:30:00Z"},
+		unavailable: []string{"01A", "01B"},
+	}
+	sn := &stubNtfy{}
+	s := newTestSeatchecker(t, c, sr, sn)
+
+	e, err := s.handler(context.Background(), Event{Airline: "ryanair", Email: "email", Password: "password", NtfyTopic: "topic"})
+	if err != nil || e.Status != 200 {
+		t.Fatalf("ryanair watch failed, status: %v, error: %v", e.Status, err)
+	}
+	if e.SeatState != (EmptySeats{7, 7, 8}) || e.Flight.Number != "FR 1234" {
+		t.Fatalf("wrong state of the watch: %+v, %+v", e.SeatState, e.Flight)
+	}
+
+	sw := &stubWizzair{occupied: []string{"1A", "1B"}}
+	s.providers["wizzair"] = newTestWizzair(t, sw)
+	e, err = s.handler(context.Background(), Event{Airline: "wizzair", Email: "email", Password: "password", NtfyTopic: "topic"})
+	if err != nil || e.Status != 200 {
+		t.Fatalf("wizzair watch failed, status: %v, error: %v", e.Status, err)
+	}
+	if e.SeatState != (EmptySeats{7, 7, 8}) || e.Flight.Number != "W6 2201" || e.Flight.Arrival != "2024-07-05T07:35:00" {
+		t.Fatalf("wrong state of the watch: %+v, %+v", e.SeatState, e.Flight)
+	}
+
+	e, _ = s.handler(context.Background(), Event{Airline: "easyjet", NtfyTopic: "topic"})
+	if e.Status != 400 {
+		t.Fatalf("expected invalid config for unknown airline, received: %v", e.Status)
+	}
+}
+
+func TestHandlerLookup(t *testing.T) {
+	c := &fakeClock{time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)}
+	sr := &stubRyanair{
+		departures:  []string{"2024-07-05T18:30:00Z"},
+		unavailable: []string{"01A"},
+	}
+	sn := &stubNtfy{}
+	s := newTestSeatchecker(t, c, sr, sn)
+	s.providers["wizzair"] = newTestWizzair(t, &stubWizzair{})
+
+	// No password is shared for the lookup.
+	e, _ := s.handler(context.Background(), Event{Pnr: " abc123", Email: "email", NtfyTopic: "topic"})
+	if e.Status != 200 {
+		t.Fatalf("lookup failed, status: %v, message: %v", e.Status, e.Message)
+	}
+	if e.Pnr != "ABC123" || e.Flight.Pnr != "ABC123" || e.SeatState != (EmptySeats{7, 8, 8}) {
+		t.Fatalf("wrong state of the watch: %v, %+v, %+v", e.Pnr, e.Flight, e.SeatState)
+	}
+
+	test := func(e Event) {
+		r, _ := s.handler(context.Background(), e)
+		if r.Status != 400 {
+			t.Fatalf("expected invalid config for %+v, received: %v", e, r.Status)
+		}
+	}
+	test(Event{Pnr: "ABC", Email: "email"})
+	test(Event{Airline: "wizzair", Pnr: "ABC123", Email: "email"})
+}
+
+func TestHandlerVerification(t *testing.T) {
+	c := &fakeClock{time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)}
+	sr := &stubRyanair{
+		departures: []string{"2024-07-05T18:30:00Z"},
+		mfa:        true,
+	}
+	sn := &stubNtfy{}
+	s := newTestSeatchecker(t, c, sr, sn)
+
+	subs := []Subscriber{{Name: "partner", NtfyTopic: "partner"}, {Name: "me", NtfyTopic: "me"}}
+	e := Event{Email: "email", Password: "password", Watch: Watch{Owner: "me", Subscribers: subs}}
+	r, _ := s.handler(context.Background(), e)
+	if r.Status != 401 {
+		t.Fatalf("wrong status, expected: 401, received: %v", r.Status)
+	}
+	// Only owner is asked for the code, nobody receives failure summary.
+	if len(sn.notifications) != 1 || sn.notifications[0].Topic != "me" ||
+		!strings.Contains(sn.notifications[0].Message, "mfa_token") {
+		t.Fatalf("owner not asked for verification, received: %v", sn.notifications)
+	}
+
+	if r.Device == "" || !strings.Contains(sn.notifications[0].Message, r.Device) {
+		t.Fatalf("device missing in verification request, received: %v", sn.notifications[0].Message)
+	}
+
+	// Request sent to /verify.
+	e.Device, e.MfaToken, e.MfaCode = r.Device, "mfa_token", "123456"
+	r, _ = s.handler(context.Background(), e)
+	if r.Status != 200 {
+		t.Fatalf("verification failed, status: %v, message: %v", r.Status, r.Message)
+	}
+	if r.Device != e.Device || sr.trusted != e.Device {
+		t.Fatalf("wrong device verified, expected: %v, received: %v, %v", e.Device, r.Device, sr.trusted)
+	}
+	if r.MfaToken != "" || r.MfaCode != "" {
+		t.Fatalf("verification kept in the event: %v, %v", r.MfaToken, r.MfaCode)
+	}
+
+	r, _ = s.handler(context.Background(), r)
+	if r.Status != 200 {
+		t.Fatalf("verified device failed to login, status: %v, message: %v", r.Status, r.Message)
+	}
+
+	r, _ = s.handler(context.Background(), Event{MfaCode: "123456"})
+	if r.Status != 400 {
+		t.Fatalf("code without token accepted, received status: %v", r.Status)
+	}
+
+	// Another watch of the same account is a different device.
+	r, _ = s.handler(context.Background(), Event{Email: "email", Password: "password", NtfyTopic: "topic"})
+	if r.Status != 401 || r.Device == e.Device {
+		t.Fatalf("new watch reused the device, status: %v, device: %v", r.Status, r.Device)
+	}
+}
+
+func TestHandlerAircraftChange(t *testing.T) {
+	c := &fakeClock{time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)}
+	sr := &stubRyanair{
+		departures:  []string{"2024-07-05T18:30:00Z"},
+		unavailable: []string{"01A"},
+		model:       "73H",
+	}
+	sn := &stubNtfy{}
+	s := newTestSeatchecker(t, c, sr, sn)
+
+	e, _ := s.handler(context.Background(), Event{NtfyTopic: "topic", NotifyOn: []string{conditionSeats}})
+	if e.Aircraft != (Aircraft{"73H", 4}) {
+		t.Fatalf("wrong aircraft, received: %v", e.Aircraft)
+	}
+	sn.notifications = nil
+
+	// Swapped aircraft has different seats taken.
+	sr.model = "7M8"
+	sr.unavailable = []string{"02B", "03C", "04D"}
+	e, _ = s.handler(context.Background(), e)
+	if e.Status != 200 {
+		t.Fatalf("wrong status, expected: 200, received: %v", e.Status)
+	}
+	if len(sn.notifications) != 1 || sn.notifications[0].Title != "Seatchecker: aircraft changed" {
+		t.Fatalf("expected only aircraft change notification, received: %v", sn.notifications)
+	}
+	if m := sn.notifications[0].Message; !strings.Contains(m, "Boeing 737-800 (24 seats) to Boeing 737-8200 (24 seats)") {
+		t.Fatalf("wrong notification, received: %v", m)
+	}
+	if len(e.History) != 1 || !reflect.DeepEqual(e.Unavailable, sr.unavailable) {
+		t.Fatalf("baseline not reset, history: %v, unavailable: %v", e.History, e.Unavailable)
+	}
+
+	// Changes on the new aircraft are reported as usual.
+	sn.notifications = nil
+	sr.unavailable = append(sr.unavailable, "04E")
+	e, _ = s.handler(context.Background(), e)
+	if len(sn.notifications) != 1 || !strings.Contains(sn.notifications[0].Message, "+1 taken") {
+		t.Fatalf("wrong notification after swap, received: %v", sn.notifications)
+	}
+}
+
+func TestHandlerBudget(t *testing.T) {
+	c := &fakeClock{time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)}
+	sr := &stubRyanair{
+		departures: []string{"2024-07-05T18:30:00Z"},
+		hang:       "/basketapi/",
+	}
+	sn := &stubNtfy{}
+	s := newTestSeatchecker(t, c, sr, sn)
+
+	// Lambda with 500ms left after the reserve.
+	ctx, cancel := context.WithTimeout(context.Background(), budgetReserve+500*time.Millisecond)
+	defer cancel()
+	e, _ := s.handler(ctx, Event{NtfyTopic: "topic"})
+	if e.Status != 500 {
+		t.Fatalf("wrong status, expected: 500, received: %v", e.Status)
+	}
+	if !strings.Contains(e.Message, "step basket exceeded its budget") {
+		t.Fatalf("wrong message, received: %v", e.Message)
+	}
+	// Share of the basket is taken from the real remaining time, regardless of the clock.
+	m := regexp.MustCompile(`budget of (\S+):`).FindStringSubmatch(e.Message)
+	if len(m) != 2 {
+		t.Fatalf("budget of the step not reported, received: %v", e.Message)
+	}
+	if b, err := time.ParseDuration(m[1]); err != nil || b > 150*time.Millisecond || b < 100*time.Millisecond {
+		t.Fatalf("wrong budget of basket, expected: ~150ms, received: %v", m[1])
+	}
+}
@@ -6,19 +6,220 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
+	"net"
 	"net/http"
 	"net/http/httptrace"
 	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"go.opentelemetry.io/contrib/instrumentation/net/http/httptrace/otelhttptrace"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 type Client struct {
-	scheme string
-	fqdn   string
+	scheme  string
+	fqdn    string
+	http    *http.Client // Shared by copies of the client, default one is used when missing.
+	headers http.Header  // Sent with every request of the client.
 }
 
+// Copy of the client sending the header with every request, e.g. authorization.
+func (c Client) withDefaultHeader(key string, value string) Client {
+	h := c.headers.Clone()
+	if h == nil {
+		h = http.Header{}
+	}
+	h.Set(key, value)
+	c.headers = h
+	return c
+}
+
+// Timeouts and proxy of the HTTP client, zero values fall back to the defaults.
+type HTTPConfig struct {
+	DialTimeout time.Duration
+	TLSTimeout  time.Duration
+	Timeout     time.Duration // Overall time of a request, including reading the response.
+	Proxy       string        // Proxy URL, environment variables are used when empty.
+}
+
+func defaultHTTPConfig() HTTPConfig {
+	return HTTPConfig{
+		DialTimeout: 5 * time.Second,
+		TLSTimeout:  5 * time.Second,
+		Timeout:     10 * time.Second,
+	}
+}
+
+// Config set in the environment of the Lambda, timeouts are durations, e.g. "3s".
+func httpConfigFromEnv(getenv func(string) string) (HTTPConfig, error) {
+	cfg := HTTPConfig{Proxy: getenv("SEATCHECKER_PROXY")}
+	for k, d := range map[string]*time.Duration{
+		"SEATCHECKER_DIAL_TIMEOUT": &cfg.DialTimeout,
+		"SEATCHECKER_TLS_TIMEOUT":  &cfg.TLSTimeout,
+		"SEATCHECKER_HTTP_TIMEOUT": &cfg.Timeout,
+	} {
+		v := getenv(k)
+		if v == "" {
+			continue
+		}
+		t, err := time.ParseDuration(v)
+		if err != nil || t <= 0 {
+			return HTTPConfig{}, fmt.Errorf("invalid %v: %v", k, v)
+		}
+		*d = t
+	}
+	return cfg, nil
+}
+
+// Long-lived client keeping the connections alive between requests of the run and warm runs of the Lambda.
+func newHTTPClient(cfg HTTPConfig) (*http.Client, error) {
+	t, err := newTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return instrumentedClient(t, cfg.Timeout), nil
+}
+
+func newTransport(cfg HTTPConfig) (*http.Transport, error) {
+	d := defaultHTTPConfig()
+	if cfg.DialTimeout == 0 {
+		cfg.DialTimeout = d.DialTimeout
+	}
+	if cfg.TLSTimeout == 0 {
+		cfg.TLSTimeout = d.TLSTimeout
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if cfg.Proxy != "" {
+		u, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse proxy: %v", err)
+		}
+		proxy = http.ProxyURL(u)
+	}
+
+	t := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   cfg.DialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout:   cfg.TLSTimeout,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          20,
+		MaxIdleConnsPerHost:   5,
+		IdleConnTimeout:       90 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	return t, nil
+}
+
+func instrumentedClient(t http.RoundTripper, timeout time.Duration) *http.Client {
+	if timeout == 0 {
+		timeout = defaultHTTPConfig().Timeout
+	}
+	return &http.Client{
+		Transport: otelhttp.NewTransport(
+			t,
+			otelhttp.WithClientTrace(func(ctx context.Context) *httptrace.ClientTrace {
+				return otelhttptrace.NewClientTrace(ctx)
+			}),
+		),
+		Timeout: timeout,
+	}
+}
+
+var defaultHTTPClient = sync.OnceValue(func() *http.Client {
+	cfg, err := httpConfigFromEnv(os.Getenv)
+	if err != nil {
+		log.Printf("Error: invalid HTTP config, defaults are used: %v\n", err)
+		cfg = defaultHTTPConfig()
+	}
+	c, err := newHTTPClient(cfg)
+	if err != nil {
+		log.Printf("Error: invalid HTTP config, proxy is ignored: %v\n", err)
+		cfg.Proxy = ""
+		c, _ = newHTTPClient(cfg)
+	}
+	return c
+})
+
+func newClient(scheme string, fqdn string, hc *http.Client) Client {
+	return Client{scheme: scheme, fqdn: fqdn, http: hc}
+}
+
+// Response headers worth reporting with the error.
+var errorHeaders = []string{"Content-Type", "Retry-After", "X-Request-Id", "X-Amzn-Requestid"}
+
+// Query parameters which are never reported.
+var secretParams = []string{"token", "password", "key", "secret", "auth"}
+
+// Length of the response body excerpt.
+const errorBodySize = 512
+
+// Response with unsuccessful status code.
+type HTTPError struct {
+	Method string
+	URL    string // Without credentials and secret query parameters.
+	Status int
+	Header http.Header // Only headers of interest.
+	Body   string      // Truncated to errorBodySize.
+
+	raw []byte // Full body, for callers decoding the error details.
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("%v %v returned %v %v", e.Method, e.URL, e.Status, http.StatusText(e.Status))
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+func redactURL(u *url.URL) string {
+	r := *u
+	r.User = nil
+	q := r.Query()
+	for k := range q {
+		for _, s := range secretParams {
+			if strings.Contains(strings.ToLower(k), s) {
+				q.Set(k, "REDACTED")
+			}
+		}
+	}
+	r.RawQuery = q.Encode()
+	return r.String()
+}
+
+func newHTTPError(req *http.Request, res *http.Response, b []byte) *HTTPError {
+	h := http.Header{}
+	for _, k := range errorHeaders {
+		if v := res.Header.Values(k); len(v) > 0 {
+			h[k] = v
+		}
+	}
+	body := strings.TrimSpace(string(b))
+	if len(body) > errorBodySize {
+		body = body[:errorBodySize] + "…"
+	}
+	return &HTTPError{
+		Method: req.Method,
+		URL:    redactURL(req.URL),
+		Status: res.StatusCode,
+		Header: h,
+		Body:   body,
+		raw:    b,
+	}
+}
+
+const userAgent = "seatchecker"
+
 type Request struct {
 	ctx         context.Context
 	method      string
@@ -27,7 +228,9 @@ type Request struct {
 	path        string
 	queryParams url.Values
 	headers     http.Header
-	body        any
+	body        any          // Raw []byte is sent as is, anything else is encoded as JSON.
+	client      *http.Client // Default client is used when missing.
+	decode      Decoder      // JSON is decoded when missing.
 }
 
 func (r Request) creator() (*http.Request, error) {
@@ -40,8 +243,12 @@ func (r Request) creator() (*http.Request, error) {
 	u.RawQuery = r.queryParams.Encode() // Specify query string parameters.
 
 	buf := []byte{} // If payload not specified, send empty buffer.
-	if r.body != nil {
-		buf, err = json.Marshal(r.body)
+	switch b := r.body.(type) {
+	case nil:
+	case []byte: // Raw payload, e.g. file upload.
+		buf = b
+	default:
+		buf, err = json.Marshal(b)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal payload: %v", err)
 		}
@@ -53,9 +260,15 @@ func (r Request) creator() (*http.Request, error) {
 	}
 
 	if r.headers != nil {
-		req.Header = r.headers
+		req.Header = r.headers.Clone()
+	}
+	// Add default headers.
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", userAgent)
 	}
-	req.Header.Add("Content-Type", "application/json") // Add default headers
 
 	return req, nil
 }
@@ -68,13 +281,9 @@ func httpsRequest[T any](req Request) (T, error) {
 		return nilT, fmt.Errorf("failed to create request: %v", err)
 	}
 
-	c := &http.Client{
-		Transport: otelhttp.NewTransport(
-			http.DefaultTransport,
-			otelhttp.WithClientTrace(func(ctx context.Context) *httptrace.ClientTrace {
-				return otelhttptrace.NewClientTrace(ctx)
-			}),
-		),
+	c := req.client
+	if c == nil {
+		c = defaultHTTPClient()
 	}
 
 	res, err := c.Do(r)
@@ -83,47 +292,123 @@ func httpsRequest[T any](req Request) (T, error) {
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode != 200 {
-		return nilT, fmt.Errorf("request return invalid code: %v", res.StatusCode)
-	}
-
 	b, err := io.ReadAll(res.Body)
 	if err != nil {
 		return nilT, fmt.Errorf("failed to read response: %v", err)
 	}
 
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nilT, newHTTPError(r, res, b)
+	}
+
+	// Nothing to decode, e.g. 204 No Content or notification of request[any].
+	// Empty response of a typed request is left to the decoder to reject.
+	if res.StatusCode == http.StatusNoContent || len(bytes.TrimSpace(b)) == 0 && isAny[T]() {
+		return nilT, nil
+	}
+
+	decode := req.decode
+	if decode == nil {
+		decode = json.Unmarshal
+	}
 	var t T
-	if err := json.Unmarshal(b, &t); err != nil {
-		return nilT, fmt.Errorf("failed to unmarshal Json response: %v", err)
+	if err := decode(b, &t); err != nil {
+		return nilT, fmt.Errorf("failed to decode response: %v", err)
 	}
 
 	return t, nil
 }
 
-func httpsRequestGet[T any](ctx context.Context, c Client, path string, queryParams url.Values, headers http.Header) (T, error) {
-	r := Request{
-		ctx,
-		"GET",
-		c.scheme,
-		c.fqdn,
-		path,
-		queryParams,
-		headers,
-		nil,
+// Response of the request is not used by the caller.
+func isAny[T any]() bool {
+	var t T
+	_, ok := any(&t).(*any)
+	return ok
+}
+
+// Options of a request, applied in order, later ones override earlier ones.
+type RequestOption func(r *Request)
+
+// Decoder of the response body, JSON by default.
+type Decoder func(b []byte, v any) error
+
+func withHeader(key string, value string) RequestOption {
+	return func(r *Request) {
+		if r.headers == nil {
+			r.headers = http.Header{}
+		}
+		r.headers.Set(key, value)
+	}
+}
+
+func withHeaders(h http.Header) RequestOption {
+	return func(r *Request) {
+		if r.headers == nil {
+			r.headers = http.Header{}
+		}
+		for k, vs := range h {
+			r.headers[http.CanonicalHeaderKey(k)] = slices.Clone(vs)
+		}
+	}
+}
+
+func withQuery(q url.Values) RequestOption {
+	return func(r *Request) {
+		r.queryParams = q
+	}
+}
+
+func withJSON(body any) RequestOption {
+	return func(r *Request) {
+		r.body = body
+		withHeader("Content-Type", "application/json")(r)
 	}
-	return httpsRequest[T](r)
 }
 
-func httpsRequestPost[T any](ctx context.Context, c Client, path string, body any) (T, error) {
+func withForm(v url.Values) RequestOption {
+	return func(r *Request) {
+		r.body = []byte(v.Encode())
+		withHeader("Content-Type", "application/x-www-form-urlencoded")(r)
+	}
+}
+
+// Raw payload, e.g. file upload.
+func withRaw(b []byte, contentType string) RequestOption {
+	return func(r *Request) {
+		r.body = b
+		withHeader("Content-Type", contentType)(r)
+	}
+}
+
+func withDecoder(d Decoder) RequestOption {
+	return func(r *Request) {
+		r.decode = d
+	}
+}
+
+// Decode plain text response into *string.
+func decodeText(b []byte, v any) error {
+	s, ok := v.(*string)
+	if !ok {
+		return fmt.Errorf("text response requires string, received: %T", v)
+	}
+	*s = string(b)
+	return nil
+}
+
+// Send request to the client, default headers of the client are sent with every request.
+func request[T any](ctx context.Context, c Client, method string, path string, opts ...RequestOption) (T, error) {
 	r := Request{
-		ctx,
-		"POST",
-		c.scheme,
-		c.fqdn,
-		path,
-		nil,
-		nil,
-		body,
+		ctx:     ctx,
+		method:  method,
+		scheme:  c.scheme,
+		fqdn:    c.fqdn,
+		path:    path,
+		headers: c.headers.Clone(),
+		client:  c.http,
+	}
+	for _, o := range opts {
+		o(&r)
 	}
 	return httpsRequest[T](r)
 }
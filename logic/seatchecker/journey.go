@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var errNoJourneys = errors.New("booking does not contain any journeys")
+var errNoUpcomingJourney = errors.New("all journeys of the booking have already departed")
+
+// RFC3339 is the format Ryanair uses for time.
+func parseDeparture(d string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, d)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error parsing time: %v", err)
+	}
+	return t.UTC(), nil
+}
+
+func formatDeparture(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Select departure of the journey which should be tracked.
+// The currently tracked journey is kept while it is part of the booking and
+// after it departed, so the handler is able to finish the watch.
+// Otherwise the earliest journey which did not depart yet is selected.
+func nextDeparture(js []string, tracked string, now time.Time) (string, error) {
+	var t time.Time
+	if tracked != "" {
+		var err error
+		t, err = parseDeparture(tracked)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse tracked departure: %v", err)
+		}
+		// Flown journeys can disappear from the booking.
+		if !t.After(now) {
+			return formatDeparture(t), nil
+		}
+	}
+
+	if len(js) == 0 {
+		return "", errNoJourneys
+	}
+
+	var next time.Time
+	for _, j := range js {
+		d, err := parseDeparture(j)
+		if err != nil {
+			return "", err
+		}
+		if tracked != "" && d.Equal(t) {
+			return formatDeparture(t), nil
+		}
+		// Journeys are not guaranteed to be ordered.
+		if d.After(now) && (next.IsZero() || d.Before(next)) {
+			next = d
+		}
+	}
+
+	if next.IsZero() {
+		return "", errNoUpcomingJourney
+	}
+	return formatDeparture(next), nil
+}
+
+// Details of the tracked flight.
+type FlightDetails struct {
+	Number      string `json:"number"`
+	Origin      string `json:"origin"`
+	Destination string `json:"destination"`
+	Departure   string `json:"departure"` // Local time of the origin airport.
+	Arrival     string `json:"arrival"`   // Local time of the destination airport.
+	Pnr         string `json:"pnr"`
+}
+
+func flightDetails(j Journey, pnr string) FlightDetails {
+	return FlightDetails{
+		Number:      j.FlightNumber,
+		Origin:      j.Orig,
+		Destination: j.Dest,
+		Departure:   j.Depart,
+		Arrival:     j.Arrive,
+		Pnr:         pnr,
+	}
+}
+
+// Time of departure, e.g. "14:05". Local time is preferred, as passengers are used to it.
+func (fd FlightDetails) departureTime() string {
+	// Ryanair uses local time without offset, e.g. "2024-07-05T14:05:00.000".
+	if len(fd.Departure) >= 16 {
+		if t, err := time.Parse("2006-01-02T15:04", fd.Departure[:16]); err == nil {
+			return t.Format("15:04")
+		}
+	}
+	return ""
+}
+
+// Describe flight, e.g. "FR1234 DUB→STN 14:05".
+func (fd FlightDetails) generateText() string {
+	var ps []string
+	if fd.Number != "" {
+		ps = append(ps, strings.ReplaceAll(fd.Number, " ", ""))
+	}
+	if fd.Origin != "" && fd.Destination != "" {
+		ps = append(ps, fd.Origin+"→"+fd.Destination)
+	}
+	if t := fd.departureTime(); t != "" {
+		ps = append(ps, t)
+	}
+	return strings.Join(ps, " ")
+}
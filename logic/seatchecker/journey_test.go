@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNextDeparture(t *testing.T) {
+	n := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	test := func(js []string, tracked string, e string) {
+		r, err := nextDeparture(js, tracked, n)
+		if err != nil {
+			t.Fatalf("failed to calculate next departure: %v", err)
+		}
+		if e != r {
+			t.Fatalf("wrong departure, expected: %v, received: %v", e, r)
+		}
+		if _, err := time.Parse(time.RFC3339, r); err != nil {
+			t.Fatalf("departure is not in RFC3339 format: %v", err)
+		}
+	}
+
+	out := "2024-06-28T08:00:00Z"
+	back := "2024-07-05T18:30:00Z"
+	later := "2024-07-10T06:15:00Z"
+
+	// Past journeys are skipped.
+	test([]string{out, back, later}, "", back)
+	// Order of journeys is not guaranteed.
+	test([]string{later, out, back}, "", back)
+	// Other time zones are normalized to UTC.
+	test([]string{"2024-07-05T20:30:00+02:00"}, "", back)
+	// Tracked journey is kept, even when it departed.
+	test([]string{out, back}, out, out)
+	test([]string{back, later}, later, later)
+	// Tracked journey disappeared from the booking after it was flown.
+	test([]string{back}, "2024-07-01T11:00:00Z", "2024-07-01T11:00:00Z")
+	test(nil, "2024-07-01T11:00:00Z", "2024-07-01T11:00:00Z")
+	// Tracked journey was rescheduled.
+	test([]string{later}, back, later)
+}
+
+func TestNextDepartureErrors(t *testing.T) {
+	n := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	if _, err := nextDeparture(nil, "", n); !errors.Is(err, errNoJourneys) {
+		t.Fatalf("wrong error, expected: %v, received: %v", errNoJourneys, err)
+	}
+	js := []string{"2024-06-28T08:00:00Z", "2024-06-30T08:00:00Z"}
+	if _, err := nextDeparture(js, "", n); !errors.Is(err, errNoUpcomingJourney) {
+		t.Fatalf("wrong error, expected: %v, received: %v", errNoUpcomingJourney, err)
+	}
+	if _, err := nextDeparture([]string{"2024-07-05 18:30"}, "", n); err == nil {
+		t.Fatal("expected error for invalid time format")
+	}
+}
+
+func TestFlightDetailsText(t *testing.T) {
+	j := Journey{
+		DepartUTC:    "2024-07-05T13:05:00Z",
+		Depart:       "2024-07-05T14:05:00.000",
+		ArriveUTC:    "2024-07-05T14:25:00Z",
+		Arrive:       "2024-07-05T14:25:00.000",
+		Orig:         "DUB",
+		Dest:         "STN",
+		FlightNumber: "FR 1234",
+	}
+
+	test := func(fd FlightDetails, e string) {
+		if r := fd.generateText(); r != e {
+			t.Fatalf("wrong output, expected: %v, received: %v", e, r)
+		}
+	}
+
+	fd := flightDetails(j, "ABC123")
+	if fd.Pnr != "ABC123" || fd.Arrival != j.Arrive {
+		t.Fatalf("wrong flight details, received: %v", fd)
+	}
+	test(fd, "FR1234 DUB→STN 14:05")
+	test(FlightDetails{Origin: "DUB", Destination: "STN"}, "DUB→STN")
+	test(FlightDetails{}, "")
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Verification resumes the watch, the code and token have to be provided together
+// with the device they were issued for.
+func (e Event) validateVerification() error {
+	if (e.MfaCode == "") != (e.MfaToken == "") {
+		return fmt.Errorf("verification requires both mfa_code and mfa_token")
+	}
+	if e.MfaCode != "" && e.Device == "" {
+		return fmt.Errorf("verification requires the device")
+	}
+	return nil
+}
+
+// Random, so the fingerprint cannot be derived from the account.
+func newDevice() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate device fingerprint: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Owner of the watch receives the verification request, as only they have access to the account.
+func (e Event) owner() Subscriber {
+	subs := e.subscribers()
+	for _, sub := range subs {
+		if e.Watch.Owner != "" && sub.Name == e.Watch.Owner {
+			return sub
+		}
+	}
+	return subs[0]
+}
+
+func verificationText(email string, device string, ch *MfaChallenge) string {
+	return strings.Join([]string{
+		fmt.Sprintf("Airline sent a verification code to %v.", email),
+		"Resume the watch by sending the original request to /verify, extended by:",
+		fmt.Sprintf(`"device": "%v", "mfa_token": "%v", "mfa_code": "<code>"`, device, ch.Token),
+	}, "\n")
+}
+
+func (s Seatchecker) requestVerification(ctx context.Context, e Event, email string, ch *MfaChallenge) error {
+	m := Message{
+		Title: "Seatchecker: verification required",
+		Text:  verificationText(email, e.Device, ch),
+	}
+	log.Println("Request verification of the device.")
+	_, err := s.fanOut(ctx, []Subscriber{e.owner()}, m)
+	return err
+}
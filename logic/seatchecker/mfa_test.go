@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateVerification(t *testing.T) {
+	test := func(e Event, ok bool) {
+		if err := e.validateVerification(); (err == nil) != ok {
+			t.Fatalf("wrong validation of %+v, received: %v", e, err)
+		}
+	}
+
+	test(Event{}, true)
+	test(Event{Device: "device", MfaToken: "token", MfaCode: "123456"}, true)
+	test(Event{MfaToken: "token", MfaCode: "123456"}, false)
+	test(Event{MfaCode: "123456"}, false)
+	test(Event{MfaToken: "token"}, false)
+}
+
+func TestOwner(t *testing.T) {
+	test := func(e Event, name string, topic string) {
+		o := e.owner()
+		if o.Name != name || o.NtfyTopic != topic {
+			t.Fatalf("wrong owner, expected: %v/%v, received: %v/%v", name, topic, o.Name, o.NtfyTopic)
+		}
+	}
+
+	test(Event{NtfyTopic: "topic"}, "", "topic")
+	subs := []Subscriber{{Name: "partner", NtfyTopic: "partner"}, {Name: "me", NtfyTopic: "me"}}
+	test(Event{Watch: Watch{Owner: "me", Subscribers: subs}}, "me", "me")
+	test(Event{Watch: Watch{Subscribers: subs}}, "partner", "partner")
+}
+
+func TestVerificationText(t *testing.T) {
+	r := verificationText("john@doe.com", "device", &MfaChallenge{Token: "mfa_token"})
+	for _, e := range []string{"john@doe.com", "/verify", `"device": "device"`, `"mfa_token": "mfa_token"`} {
+		if !strings.Contains(r, e) {
+			t.Fatalf("missing %v in text: %v", e, r)
+		}
+	}
+}
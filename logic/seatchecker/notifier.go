@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"fmt"
+	"mime"
+	"net/http"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -16,7 +18,22 @@ type Notification struct {
 	Tags    []string `json:"tags"`
 }
 
-func (c Client) sendNotification(ctx context.Context, topic string, text string) error {
+// Notification sent to subscribers, optionally with a file attached.
+type Message struct {
+	Title      string
+	Text       string
+	Filename   string
+	Attachment []byte
+}
+
+func (c Client) send(ctx context.Context, topic string, m Message) error {
+	if m.Attachment != nil {
+		return c.sendAttachment(ctx, topic, m.Title, m.Text, m.Filename, m.Attachment)
+	}
+	return c.sendNotification(ctx, topic, m.Title, m.Text)
+}
+
+func (c Client) sendNotification(ctx context.Context, topic string, title string, text string) error {
 	ctx, span := tr.Start(ctx, "notifier_send_notification")
 	defer span.End()
 	span.SetAttributes(attribute.String("topic", topic), attribute.String("text", text)) // NOTE: delete after testing.
@@ -24,11 +41,11 @@ func (c Client) sendNotification(ctx context.Context, topic string, text string)
 	b := Notification{
 		Topic:   topic,
 		Message: text,
-		Title:   "Seatchecker",
+		Title:   title,
 		Tags:    []string{"airplane"},
 	}
 
-	_, err := httpsRequestPost[any](ctx, c, "/", b)
+	_, err := request[any](ctx, c, "POST", "/", withJSON(b))
 	if err != nil {
 		err = fmt.Errorf("failed to send notification: %v", err)
 		span.RecordError(err, trace.WithStackTrace(true))
@@ -39,3 +56,29 @@ func (c Client) sendNotification(ctx context.Context, topic string, text string)
 
 	return nil
 }
+
+// Send notification with a file attached, ntfy expects the file as the body of the request.
+func (c Client) sendAttachment(ctx context.Context, topic string, title string, text string, filename string, file []byte) error {
+	ctx, span := tr.Start(ctx, "notifier_send_attachment")
+	defer span.End()
+	span.SetAttributes(attribute.String("topic", topic), attribute.String("filename", filename))
+
+	// Headers have to be encoded, as the text contains new lines.
+	h := http.Header{
+		"Title":    {mime.BEncoding.Encode("UTF-8", title)},
+		"Message":  {mime.BEncoding.Encode("UTF-8", text)},
+		"Tags":     {"airplane"},
+		"Filename": {filename},
+	}
+
+	_, err := request[any](ctx, c, "PUT", topic, withHeaders(h), withRaw(file, "application/octet-stream"))
+	if err != nil {
+		err = fmt.Errorf("failed to send attachment: %v", err)
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	span.AddEvent("attachment sent")
+
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -28,6 +29,9 @@ func TestSendNotification(t *testing.T) {
 		if b["message"] != m {
 			t.Fatalf("wrong message, expected: %v, received: %v", m, b["message"])
 		}
+		if b["title"] != "test_title" {
+			t.Fatalf("wrong title, expected: test_title, received: %v", b["title"])
+		}
 
 		// Create fake response
 		fmt.Fprintln(w, "{}")
@@ -36,8 +40,46 @@ func TestSendNotification(t *testing.T) {
 
 	// Check received response
 	c := Client{scheme: "http", fqdn: ts.URL}
-	err := c.sendNotification(context.Background(), tp, m)
+	err := c.sendNotification(context.Background(), tp, "test_title", m)
 	if err != nil {
 		t.Fatalf("failed to send notification: %v", err)
 	}
 }
+
+func TestSendAttachment(t *testing.T) {
+	tp := "test_topic"
+	m := "test_text\nsecond_line"
+	f := []byte("test_file")
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Check request
+		if r.Method != "PUT" {
+			t.Fatalf("wrong http method, expected: PUT, received: %v", r.Method)
+		}
+		if r.URL.Path != "/"+tp {
+			t.Fatalf("wrong path, expected: /%v, received: %v", tp, r.URL.Path)
+		}
+		if fn := r.Header.Get("Filename"); fn != "seatmap.png" {
+			t.Fatalf("wrong filename, expected: seatmap.png, received: %v", fn)
+		}
+		rm, _ := new(mime.WordDecoder).DecodeHeader(r.Header.Get("Message"))
+		if rm != m {
+			t.Fatalf("wrong message, expected: %v, received: %v", m, rm)
+		}
+		b, _ := io.ReadAll(r.Body)
+		if string(b) != string(f) {
+			t.Fatalf("wrong attachment, expected: %v, received: %v", string(f), string(b))
+		}
+
+		// Create fake response
+		fmt.Fprintln(w, "{}")
+	}))
+	defer ts.Close()
+
+	// Check received response
+	c := Client{scheme: "http", fqdn: ts.URL}
+	err := c.sendAttachment(context.Background(), tp, "test_title", m, "seatmap.png", f)
+	if err != nil {
+		t.Fatalf("failed to send attachment: %v", err)
+	}
+}
@@ -2,11 +2,14 @@ package main
 
 import (
 	"context"
+	"os"
 	"testing"
 )
 
 // NOTE: This is executed as a setup before the rest of the test suite.
 func TestMain(m *testing.M) {
+	// Collector is not running in tests, do not wait for export of traces.
+	os.Setenv("OTEL_EXPORTER_OTLP_TIMEOUT", "10")
 	defer setupOtel(context.Background())()
 	m.Run()
 }
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"time"
+	_ "time/tzdata" // Lambda runtime does not ship time zone database.
+)
+
+// Limits on how often a subscriber is notified.
+type Policy struct {
+	MinInterval string     `json:"min_interval"` // Minimum time between notifications, e.g. "30m".
+	Hysteresis  int        `json:"hysteresis"`   // Minimum change of empty seats since the last notification.
+	QuietHours  QuietHours `json:"quiet_hours"`
+	TimeZone    string     `json:"time_zone"` // IANA time zone of quiet hours, defaults to UTC.
+}
+
+// Part of the day without notifications, e.g. from "22:00" to "07:00".
+type QuietHours struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Last notification received by a subscriber.
+type Delivery struct {
+	Time  string     `json:"time"`
+	Seats EmptySeats `json:"seats"`
+}
+
+func (p Policy) active() bool {
+	return p.MinInterval != "" || p.Hysteresis > 0 || p.QuietHours.From != ""
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day: %v", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (p Policy) validate() error {
+	if p.MinInterval != "" {
+		if _, err := time.ParseDuration(p.MinInterval); err != nil {
+			return fmt.Errorf("invalid min interval: %v", p.MinInterval)
+		}
+	}
+	if p.Hysteresis < 0 {
+		return fmt.Errorf("invalid hysteresis: %v", p.Hysteresis)
+	}
+	if p.QuietHours.From != "" || p.QuietHours.To != "" {
+		if _, err := parseClock(p.QuietHours.From); err != nil {
+			return err
+		}
+		if _, err := parseClock(p.QuietHours.To); err != nil {
+			return err
+		}
+	}
+	if _, err := time.LoadLocation(p.TimeZone); err != nil {
+		return fmt.Errorf("invalid time zone: %v", p.TimeZone)
+	}
+	return nil
+}
+
+// Quiet hours can span midnight.
+func (p Policy) quiet(now time.Time) bool {
+	if p.QuietHours.From == "" {
+		return false
+	}
+	// Validated before.
+	loc, _ := time.LoadLocation(p.TimeZone)
+	from, _ := parseClock(p.QuietHours.From)
+	to, _ := parseClock(p.QuietHours.To)
+
+	l := now.In(loc)
+	d := time.Duration(l.Hour())*time.Hour + time.Duration(l.Minute())*time.Minute
+	if from <= to {
+		return d >= from && d < to
+	}
+	return d >= from || d < to
+}
+
+func seatsDelta(a EmptySeats, b EmptySeats) int {
+	abs := func(x int) int { return max(x, -x) }
+	return abs(a.Window-b.Window) + abs(a.Middle-b.Middle) + abs(a.Aisle-b.Aisle)
+}
+
+// Decide whether the subscriber should be notified now, returns reason of suppression.
+func (p Policy) allows(d Delivery, now time.Time, es EmptySeats, triggered []string) (bool, string) {
+	if p.quiet(now) {
+		return false, "quiet hours"
+	}
+	if d.Time == "" {
+		return true, ""
+	}
+	if p.MinInterval != "" {
+		mi, _ := time.ParseDuration(p.MinInterval)
+		t, err := time.Parse(time.RFC3339, d.Time)
+		if err == nil && now.Sub(t) < mi {
+			return false, "min interval"
+		}
+	}
+	// Hysteresis only dampens oscillating counts, other conditions are always delivered.
+	if p.Hysteresis > 0 && len(triggered) == 1 && triggered[0] == conditionSeats {
+		if seatsDelta(d.Seats, es) < p.Hysteresis {
+			return false, "hysteresis"
+		}
+	}
+	return true, ""
+}
+
+// Seats changed since the subscriber was notified, e.g. during quiet hours.
+// Subscriber who was never notified is still waiting for the first notification.
+func (d Delivery) pending(es EmptySeats) bool {
+	return d.Time == "" || d.Seats != es
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPolicyValidate(t *testing.T) {
+	valid := []Policy{
+		{},
+		{MinInterval: "30m", Hysteresis: 2},
+		{QuietHours: QuietHours{"22:00", "07:00"}, TimeZone: "Europe/Dublin"},
+	}
+	for _, p := range valid {
+		if err := p.validate(); err != nil {
+			t.Fatalf("valid policy rejected: %v", err)
+		}
+	}
+
+	invalid := []Policy{
+		{MinInterval: "half an hour"},
+		{Hysteresis: -1},
+		{QuietHours: QuietHours{"22:00", ""}},
+		{QuietHours: QuietHours{"25:00", "07:00"}},
+		{TimeZone: "Europe/Atlantis"},
+	}
+	for _, p := range invalid {
+		if err := p.validate(); err == nil {
+			t.Fatalf("invalid policy accepted: %v", p)
+		}
+	}
+}
+
+func TestPolicyQuiet(t *testing.T) {
+	p := Policy{QuietHours: QuietHours{"22:00", "07:00"}, TimeZone: "Europe/Bratislava"}
+
+	test := func(now time.Time, e bool) {
+		if r := p.quiet(now); r != e {
+			t.Fatalf("wrong quiet hours at %v, expected: %v, received: %v", now, e, r)
+		}
+	}
+
+	// Bratislava is two hours ahead of UTC in summer.
+	test(time.Date(2024, 7, 1, 19, 59, 0, 0, time.UTC), false)
+	test(time.Date(2024, 7, 1, 20, 0, 0, 0, time.UTC), true)
+	test(time.Date(2024, 7, 2, 4, 59, 0, 0, time.UTC), true)
+	test(time.Date(2024, 7, 2, 5, 0, 0, 0, time.UTC), false)
+
+	p = Policy{QuietHours: QuietHours{"12:00", "13:00"}}
+	test(time.Date(2024, 7, 1, 12, 30, 0, 0, time.UTC), true)
+	test(time.Date(2024, 7, 1, 13, 30, 0, 0, time.UTC), false)
+}
+
+func TestPolicyAllows(t *testing.T) {
+	n := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	d := Delivery{Time: "2024-07-01T11:40:00Z", Seats: EmptySeats{10, 10, 10}}
+	seats := []string{conditionSeats}
+
+	test := func(p Policy, d Delivery, es EmptySeats, triggered []string, e bool) {
+		if r, why := p.allows(d, n, es, triggered); r != e {
+			t.Fatalf("wrong decision of %v, expected: %v, received: %v (%v)", p, e, r, why)
+		}
+	}
+
+	test(Policy{MinInterval: "30m"}, d, EmptySeats{10, 8, 10}, seats, false)
+	test(Policy{MinInterval: "15m"}, d, EmptySeats{10, 8, 10}, seats, true)
+	test(Policy{Hysteresis: 3}, d, EmptySeats{10, 8, 10}, seats, false)
+	test(Policy{Hysteresis: 3}, d, EmptySeats{9, 8, 10}, seats, true)
+	// Other conditions are not dampened.
+	test(Policy{Hysteresis: 3}, d, EmptySeats{10, 9, 10}, []string{conditionSeats, conditionGroup}, true)
+	// First notification is always delivered.
+	test(Policy{MinInterval: "30m", Hysteresis: 3}, Delivery{}, EmptySeats{10, 9, 10}, seats, true)
+}
+
+func TestDeliveryPending(t *testing.T) {
+	d := Delivery{Time: "2024-07-01T11:40:00Z", Seats: EmptySeats{10, 10, 10}}
+	if d.pending(EmptySeats{10, 10, 10}) {
+		t.Fatal("delivery without change of seats is pending")
+	}
+	if !d.pending(EmptySeats{10, 9, 10}) {
+		t.Fatal("delivery with change of seats is not pending")
+	}
+	if !(Delivery{}).pending(EmptySeats{10, 9, 10}) {
+		t.Fatal("subscriber without delivery is not pending")
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Seats the user wants to sit on, either listed explicitly or described by a profile.
+type SeatPreference struct {
+	Seats   []string `json:"seats"`    // Specific seats, e.g. "17A".
+	Types   []string `json:"types"`    // Window, middle or aisle.
+	MaxRow  int      `json:"max_row"`  // Zero means any row.
+	NoExits bool     `json:"no_exits"` // Skip over-wing exit rows.
+}
+
+var seatPattern = regexp.MustCompile(`^(\d{1,2})([A-F])$`)
+
+// Normalize seat to the Ryanair designation, e.g. "7a" -> "07A".
+func normalizeSeat(s string) (string, error) {
+	m := seatPattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(s)))
+	if m == nil {
+		return "", fmt.Errorf("invalid seat: %v", s)
+	}
+	r, _ := strconv.Atoi(m[1])
+	return seatId(r, m[2]), nil
+}
+
+func (p SeatPreference) active() bool {
+	return len(p.Seats) > 0 || len(p.Types) > 0 || p.MaxRow > 0 || p.NoExits
+}
+
+func (p SeatPreference) validate() error {
+	for _, s := range p.Seats {
+		if _, err := normalizeSeat(s); err != nil {
+			return err
+		}
+	}
+	for _, t := range p.Types {
+		switch t {
+		case "window", "middle", "aisle":
+		default:
+			return fmt.Errorf("invalid seat type: %v", t)
+		}
+	}
+	if p.MaxRow < 0 {
+		return fmt.Errorf("invalid max row: %v", p.MaxRow)
+	}
+	return nil
+}
+
+func (p SeatPreference) matches(row int, column string, model string) bool {
+	if len(p.Types) > 0 && !slices.Contains(p.Types, seatType(column)) {
+		return false
+	}
+	if p.MaxRow > 0 && row > p.MaxRow {
+		return false
+	}
+	if p.NoExits && slices.Contains(exitRows[model], row) {
+		return false
+	}
+	return true
+}
+
+// Free seats matching the preference, ordered by rows.
+func (p SeatPreference) freeSeats(sm SeatMap, model string) []string {
+	var fs []string
+	for row := 1; row <= sm.Rows; row++ {
+		for _, c := range seatColumns {
+			if p.matches(row, c, model) && sm.free(row, c) {
+				fs = append(fs, seatId(row, c))
+			}
+		}
+	}
+	if len(p.Seats) == 0 {
+		return fs
+	}
+
+	var ss []string
+	for _, s := range p.Seats {
+		// Validated before.
+		n, _ := normalizeSeat(s)
+		if slices.Contains(fs, n) && !slices.Contains(ss, n) {
+			ss = append(ss, n)
+		}
+	}
+	slices.Sort(ss)
+	return ss
+}
+
+// Describe seats which were taken since the previous run, empty when nothing relevant changed.
+func preferenceChangeText(prev []string, curr []string) string {
+	var taken []string
+	for _, s := range prev {
+		if !slices.Contains(curr, s) {
+			taken = append(taken, s)
+		}
+	}
+	if len(taken) == 0 {
+		return ""
+	}
+
+	ls := []string{"Preferred seats taken: " + strings.Join(taken, ", ")}
+	switch len(curr) {
+	case 0:
+		ls = append(ls, "All preferred seats are taken")
+	case 1:
+		ls = append(ls, "Last preferred seat remaining: "+curr[0])
+	}
+	return strings.Join(ls, "\n")
+}
+
+func preferenceText(curr []string) string {
+	if len(curr) > 5 {
+		return fmt.Sprintf("Preferred seats free: %v", len(curr))
+	}
+	if len(curr) == 0 {
+		return "Preferred seats free: none"
+	}
+	return "Preferred seats free: " + strings.Join(curr, ", ")
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeSeat(t *testing.T) {
+	test := func(s string, e string) {
+		r, err := normalizeSeat(s)
+		if err != nil {
+			t.Fatalf("failed to normalize seat: %v", err)
+		}
+		if e != r {
+			t.Fatalf("wrong seat, expected: %v, received: %v", e, r)
+		}
+	}
+
+	test("17A", "17A")
+	test("7f", "07F")
+	test(" 01C ", "01C")
+
+	for _, s := range []string{"", "A17", "17G", "123A"} {
+		if _, err := normalizeSeat(s); err == nil {
+			t.Fatalf("expected error for invalid seat: %v", s)
+		}
+	}
+}
+
+func TestFreeSeats(t *testing.T) {
+	sm := newSeatMap(18, []string{"01A", "17A", "18C"})
+
+	test := func(p SeatPreference, e []string) {
+		if err := p.validate(); err != nil {
+			t.Fatalf("invalid preference: %v", err)
+		}
+		r := p.freeSeats(sm, "73H")
+		if !reflect.DeepEqual(e, r) {
+			t.Fatalf("wrong free seats, expected: %v, received: %v", e, r)
+		}
+	}
+
+	test(SeatPreference{Seats: []string{"18A", "17a", "17F", "18F"}}, []string{"17F", "18A", "18F"})
+	test(SeatPreference{Types: []string{"window"}, MaxRow: 3}, []string{"01F", "02A", "02F", "03A", "03F"})
+	test(SeatPreference{Types: []string{"aisle"}, MaxRow: 1}, []string{"01C", "01D"})
+	test(SeatPreference{Seats: []string{"01A"}}, nil)
+	test(SeatPreference{Seats: []string{"17F", "18D"}, NoExits: true}, []string{"18D"})
+
+	if err := (SeatPreference{Types: []string{"cockpit"}}).validate(); err == nil {
+		t.Fatal("expected error for invalid seat type")
+	}
+}
+
+func TestPreferenceChangeText(t *testing.T) {
+	test := func(prev []string, curr []string, e string) {
+		if r := preferenceChangeText(prev, curr); r != e {
+			t.Fatalf("wrong output, expected: %v, received: %v", e, r)
+		}
+	}
+
+	test([]string{"17A", "17F", "18A"}, []string{"17A", "17F", "18A"}, "")
+	test([]string{"17A", "17F", "18A"}, []string{"17A", "18A"}, "Preferred seats taken: 17F")
+	test([]string{"17A", "17F", "18A"}, []string{"18A"}, "Preferred seats taken: 17A, 17F\nLast preferred seat remaining: 18A")
+	test([]string{"18A"}, nil, "Preferred seats taken: 18A\nAll preferred seats are taken")
+	// First run does not have previous state.
+	test(nil, []string{"18A"}, "")
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var errNoBookings = errors.New("account does not have any active bookings")
+
+// Reservation numbers are 6 characters long, e.g. "ABC123".
+var pnrRe = regexp.MustCompile(`^[A-Z0-9]{6}$`)
+
+// Airline providing bookings and seats of their flights.
+type SeatProvider interface {
+	login(ctx context.Context, email string, password string, device string) (Auth, error) // Device identifies the watch to the airline.
+	bookings(ctx context.Context, a Auth) ([]string, error)
+	trip(ctx context.Context, a Auth, id string) (TripInfo, error)
+	occupiedSeats(ctx context.Context, a Auth, ti TripInfo, j Journey, ss Session) (FlightInfo, Session, error)
+	seatMap(ctx context.Context, model string) (int, error) // Number of rows of the aircraft.
+}
+
+// Airline able to find a booking by its reservation number, without account login.
+// Allows to watch flights booked by someone else.
+type BookingLookup interface {
+	lookup(ctx context.Context, pnr string, email string) (TripInfo, error)
+}
+
+func normalizePnr(pnr string) (string, error) {
+	n := strings.ToUpper(strings.TrimSpace(pnr))
+	if !pnrRe.MatchString(n) {
+		return "", fmt.Errorf("invalid reservation number: %v", pnr)
+	}
+	return n, nil
+}
+
+// Login of an unknown device, which has to be verified by the user.
+type MfaChallenge struct {
+	Token string
+}
+
+func (ch *MfaChallenge) Error() string {
+	return "account login requires verification of the device"
+}
+
+// Airline able to finish login after the verification challenge.
+type Verifier interface {
+	verify(ctx context.Context, email string, device string, token string, code string) (Auth, error)
+}
+
+// State of the airline kept between runs, e.g. Ryanair basket used to query the seats.
+type Session struct {
+	BasketId string `json:"basket_id"`
+	TripId   string `json:"trip_id"`
+}
+
+// Session can be reused only for the trip it was created for.
+func (ss Session) reusable(ti TripInfo) bool {
+	return ss.BasketId != "" && ss.TripId == ti.TripId
+}
+
+// Seats of the flight together with the booking they were queried for.
+type Flight struct {
+	Empty   EmptySeats
+	Rows    int
+	Info    FlightInfo
+	Trip    TripInfo
+	Session Session
+}
+
+// Bookings fetched at once by default, keeps the Lambda within its timeout without flooding the airline.
+const defaultConcurrency = 3
+const maxConcurrency = 10
+
+func validateConcurrency(n int) error {
+	if n < 0 || n > maxConcurrency {
+		return fmt.Errorf("concurrency has to be between 0 and %v, received: %v", maxConcurrency, n)
+	}
+	return nil
+}
+
+// Booking of the account together with its trip.
+type Booking struct {
+	Id   string
+	Trip TripInfo
+}
+
+type bookingResult struct {
+	id   string
+	trip TripInfo
+	err  error
+}
+
+// Fetch every booking with at most n of them in flight, results keep the order of the bookings.
+func fetchBookings(ctx context.Context, ids []string, n int, fetch func(ctx context.Context, id string) (TripInfo, error)) []bookingResult {
+	if n <= 0 {
+		n = defaultConcurrency
+	}
+	rs := make([]bookingResult, len(ids))
+	sem := make(chan struct{}, n)
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			ti, err := fetch(ctx, id)
+			rs[i] = bookingResult{id, ti, err}
+		}()
+	}
+	wg.Wait()
+	return rs
+}
+
+// Trips of every booking of the account. Failing booking does not fail the others,
+// error is returned only when none of them succeeded.
+// Once the watch tracks a booking, only that one is fetched and its failure fails the run.
+func getTrips(ctx context.Context, p SeatProvider, a Auth, concurrency int, tracked string) ([]Booking, error) {
+	ctx, span := tr.Start(ctx, "get_trips")
+	defer span.End()
+	span.SetAttributes(attribute.String("customer_id", a.CustomerID)) // NOTE: delete after testing.
+
+	throwErr := func(err error) ([]Booking, error) {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	log.Println("Get Booking IDs.")
+	ids, err := runStep(ctx, stepBookings, func(ctx context.Context) ([]string, error) {
+		return p.bookings(ctx, a)
+	})
+	if err != nil {
+		err = fmt.Errorf("get booking ID failed: %v", err)
+		return throwErr(err)
+	}
+	if len(ids) == 0 {
+		return throwErr(errNoBookings)
+	}
+	span.AddEvent("Booking IDs retrieved successfully.", trace.WithAttributes(
+		attribute.Int("bookings", len(ids))))
+	if tracked != "" {
+		// Flown or cancelled booking is no longer listed.
+		if !slices.Contains(ids, tracked) {
+			span.AddEvent("Tracked booking is no longer part of the account.")
+			return nil, nil
+		}
+		ids = []string{tracked}
+	}
+
+	rs := fetchBookings(ctx, ids, concurrency, func(ctx context.Context, id string) (TripInfo, error) {
+		log.Printf("Get Trip info of booking %v.\n", id)
+		ti, err := runStep(ctx, stepTrip, func(ctx context.Context) (TripInfo, error) {
+			return p.trip(ctx, a, id)
+		})
+		if err != nil {
+			return TripInfo{}, fmt.Errorf("get trip info failed: %v", err)
+		}
+		return ti, nil
+	})
+
+	var bs []Booking
+	var errs []error
+	for _, r := range rs {
+		if r.err != nil {
+			err := fmt.Errorf("booking %v: %v", r.id, r.err)
+			log.Printf("Error: %v\n", err)
+			span.RecordError(err)
+			errs = append(errs, err)
+			continue
+		}
+		bs = append(bs, Booking{r.id, r.trip})
+	}
+	if len(bs) == 0 {
+		return throwErr(errors.Join(errs...))
+	}
+	span.AddEvent("Bookings retrieved successfully.", trace.WithAttributes(
+		attribute.Int("failed", len(errs))))
+
+	return bs, nil
+}
+
+// Trip of the tracked departure, or of the upcoming one when nothing is tracked yet.
+// Seats are queried only for this trip, so baskets are not created for the others.
+func selectTrip(bs []Booking, tracked string, now time.Time) (Booking, string, error) {
+	var js []string
+	for _, b := range bs {
+		js = append(js, b.Trip.departures()...)
+	}
+	d, err := nextDeparture(js, tracked, now)
+	if err != nil {
+		return Booking{}, "", err
+	}
+	for _, b := range bs {
+		if b.Trip.journey(d) != (Journey{}) {
+			return b, d, nil
+		}
+	}
+	// Departed journey is no longer part of any booking.
+	if len(bs) == 0 {
+		return Booking{}, d, nil
+	}
+	return bs[0], d, nil
+}
+
+func lookupTrip(ctx context.Context, l BookingLookup, pnr string, email string) (TripInfo, error) {
+	ctx, span := tr.Start(ctx, "lookup_trip")
+	defer span.End()
+	span.SetAttributes(attribute.String("pnr", pnr))
+
+	log.Println("Look up booking by reservation number.")
+	ti, err := runStep(ctx, stepTrip, func(ctx context.Context) (TripInfo, error) {
+		return l.lookup(ctx, pnr, email)
+	})
+	if err != nil {
+		err = fmt.Errorf("booking lookup failed: %v", err)
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return TripInfo{}, err
+	}
+	span.AddEvent("Booking found successfully.")
+
+	return ti, nil
+}
+
+// Seats of the tracked journey of the trip, e.g. of the return flight once the outbound departed.
+func tripSeats(ctx context.Context, p SeatProvider, sc *SeatMapCache, a Auth, ti TripInfo, j Journey, ss Session) (Flight, error) {
+	ctx, span := tr.Start(ctx, "trip_seats")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("trip_id", ti.TripId),
+		attribute.String("flight_number", j.FlightNumber))
+
+	throwErr := func(err error) (Flight, error) {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return Flight{}, err
+	}
+
+	log.Println("Get occupied seats.")
+	var fi FlightInfo
+	ss, err := runStep(ctx, stepBasket, func(ctx context.Context) (Session, error) {
+		var err error
+		fi, ss, err = p.occupiedSeats(ctx, a, ti, j, ss)
+		return ss, err
+	})
+	if err != nil {
+		err = fmt.Errorf("get occupied seats failed: %v", err)
+		return throwErr(err)
+	}
+	span.AddEvent("Flight info retrieved successfully.")
+
+	log.Println("Get number of rows in the plane.")
+	nor, err := runStep(ctx, stepSeatMap, func(ctx context.Context) (int, error) {
+		return sc.rows(ctx, p, fi.EquipmentModel)
+	})
+	if err != nil {
+		err = fmt.Errorf("get number of rows in the plane failed: %v", err)
+		return throwErr(err)
+	}
+	span.AddEvent("Number of rows retrieved successfully.")
+
+	log.Println("Calculate number of empty seats.")
+	es := calculateEmptySeats(nor, fi.UnavailableSeats)
+	span.AddEvent("Empty seats calculated successfully.", trace.WithAttributes(
+		attribute.Int("window", es.Window),
+		attribute.Int("middle", es.Middle),
+		attribute.Int("aisle", es.Aisle)))
+
+	return Flight{Empty: es, Rows: nor, Info: fi, Trip: ti, Session: ss}, nil
+}
+
+func (ti TripInfo) departures() []string {
+	var js []string
+	for _, j := range ti.Journeys {
+		js = append(js, j.DepartUTC)
+	}
+	return js
+}
+
+// Journey departing at the given time, empty when it is not part of the booking.
+func (ti TripInfo) journey(departure string) Journey {
+	for _, j := range ti.Journeys {
+		d, err := parseDeparture(j.DepartUTC)
+		if err != nil {
+			continue
+		}
+		if formatDeparture(d) == departure {
+			return j
+		}
+	}
+	return Journey{}
+}
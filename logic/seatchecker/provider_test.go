@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetEmptySeats(t *testing.T) {
+	rs := httptest.NewServer(&stubRyanair{
+		departures:  []string{"2024-07-05T18:30:00Z"},
+		unavailable: []string{"01A", "02B", "03C"},
+	})
+	t.Cleanup(rs.Close)
+	rc := Client{scheme: "http", fqdn: rs.URL}
+
+	test := func(name string, p SeatProvider, e EmptySeats) {
+		ctx := context.Background()
+		a, err := p.login(ctx, "email", "password", "device")
+		if err != nil {
+			t.Fatalf("%v: failed to login: %v", name, err)
+		}
+		bs, err := getTrips(ctx, p, a, 0, "")
+		if err != nil {
+			t.Fatalf("%v: failed to get trips: %v", name, err)
+		}
+		if len(bs) != 1 || len(bs[0].Trip.departures()) == 0 {
+			t.Fatalf("%v: wrong trips, received: %+v", name, bs)
+		}
+		ti := bs[0].Trip
+		f, err := tripSeats(ctx, p, nil, a, ti, ti.Journeys[0], Session{})
+		if err != nil {
+			t.Fatalf("%v: failed to get empty seats: %v", name, err)
+		}
+		if f.Empty != e {
+			t.Fatalf("%v: wrong empty seats, expected: %v, received: %v", name, e, f.Empty)
+		}
+		if f.Rows != 4 {
+			t.Fatalf("%v: wrong flight, received: %+v", name, f)
+		}
+	}
+
+	test("ryanair", Ryanair{mobile: rc, browser: rc}, EmptySeats{7, 7, 7})
+	test("wizzair", newTestWizzair(t, &stubWizzair{occupied: []string{"1A", "1F", "4E"}}), EmptySeats{6, 7, 8})
+}
+
+func TestNormalizePnr(t *testing.T) {
+	test := func(pnr string, e string, ok bool) {
+		r, err := normalizePnr(pnr)
+		if (err == nil) != ok {
+			t.Fatalf("wrong validation of %v, received error: %v", pnr, err)
+		}
+		if r != e {
+			t.Fatalf("wrong reservation number, expected: %v, received: %v", e, r)
+		}
+	}
+
+	test("ABC123", "ABC123", true)
+	test(" abc123 ", "ABC123", true)
+	test("ABC12", "", false)
+	test("ABC-23", "", false)
+}
+
+func TestSessionReusable(t *testing.T) {
+	ti := TripInfo{TripId: "trip_id"}
+	test := func(ss Session, e bool) {
+		if r := ss.reusable(ti); r != e {
+			t.Fatalf("wrong reusability of %v, expected: %v, received: %v", ss, e, r)
+		}
+	}
+
+	test(Session{}, false)
+	test(Session{BasketId: "basket_id", TripId: "trip_id"}, true)
+	test(Session{BasketId: "basket_id", TripId: "other_trip"}, false)
+	test(Session{TripId: "trip_id"}, false)
+}
+
+func TestFetchBookings(t *testing.T) {
+	ids := []string{"a", "b", "c", "d", "e", "f", "g"}
+	var active, peak atomic.Int32
+	fetch := func(ctx context.Context, id string) (TripInfo, error) {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		if id == "c" {
+			return TripInfo{}, errors.New("broken booking")
+		}
+		return TripInfo{TripId: id}, nil
+	}
+
+	rs := fetchBookings(context.Background(), ids, 2, fetch)
+	if p := peak.Load(); p > 2 {
+		t.Fatalf("concurrency not bounded, expected at most: 2, received: %v", p)
+	}
+	for i, r := range rs {
+		if r.id != ids[i] {
+			t.Fatalf("wrong order of results, expected: %v, received: %v", ids[i], r.id)
+		}
+		if (r.err != nil) != (r.id == "c") {
+			t.Fatalf("error not isolated to booking c, received: %v for %v", r.err, r.id)
+		}
+		if r.err == nil && r.trip.TripId != r.id {
+			t.Fatalf("wrong trip of booking %v, received: %v", r.id, r.trip.TripId)
+		}
+	}
+}
+
+// Provider with a trip per booking, some of them failing.
+type stubBookings struct {
+	stubSeatMap
+	trips  map[string]string // Departure by booking.
+	broken string
+}
+
+func (sb *stubBookings) bookings(ctx context.Context, a Auth) ([]string, error) {
+	var ids []string
+	for id := range sb.trips {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (sb *stubBookings) trip(ctx context.Context, a Auth, id string) (TripInfo, error) {
+	if id == sb.broken {
+		return TripInfo{}, fmt.Errorf("trip %v unavailable", id)
+	}
+	return TripInfo{TripId: id, Journeys: []Journey{{DepartUTC: sb.trips[id]}}}, nil
+}
+
+func TestGetTripsIsolation(t *testing.T) {
+	ctx := context.Background()
+	sb := &stubBookings{
+		stubSeatMap: stubSeatMap{rows: 4},
+		trips:       map[string]string{"a": "2024-07-05T18:30:00Z", "b": "2024-07-03T08:00:00Z"},
+		broken:      "b",
+	}
+
+	bs, err := getTrips(ctx, sb, Auth{}, 2, "")
+	if err != nil {
+		t.Fatalf("failing booking failed the others: %v", err)
+	}
+	if len(bs) != 1 || bs[0].Id != "a" || bs[0].Trip.TripId != "a" {
+		t.Fatalf("wrong trips, received: %v", bs)
+	}
+
+	// Tracked booking is not replaced by the others.
+	_, err = getTrips(ctx, sb, Auth{}, 2, "b")
+	if err == nil || !strings.Contains(err.Error(), "trip b unavailable") {
+		t.Fatalf("expected error of the tracked booking, received: %v", err)
+	}
+	bs, err = getTrips(ctx, sb, Auth{}, 2, "c")
+	if err != nil || len(bs) != 0 {
+		t.Fatalf("expected no trips of the missing booking, received: %v, %v", bs, err)
+	}
+
+	sb.trips = map[string]string{"b": "2024-07-03T08:00:00Z"}
+	_, err = getTrips(ctx, sb, Auth{}, 2, "")
+	if err == nil || !strings.Contains(err.Error(), "trip b unavailable") {
+		t.Fatalf("expected error of the only booking, received: %v", err)
+	}
+}
+
+func TestSelectTrip(t *testing.T) {
+	now := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	trip := func(id string, ds ...string) Booking {
+		ti := TripInfo{TripId: id}
+		for _, d := range ds {
+			ti.Journeys = append(ti.Journeys, Journey{DepartUTC: d})
+		}
+		return Booking{id, ti}
+	}
+	bs := []Booking{
+		trip("later", "2024-07-10T08:00:00Z", "2024-07-12T08:00:00Z"),
+		trip("sooner", "2024-06-28T08:00:00Z", "2024-07-05T18:30:00Z"),
+	}
+
+	test := func(tracked string, id string, d string) {
+		b, rd, err := selectTrip(bs, tracked, now)
+		if err != nil {
+			t.Fatalf("failed to select trip: %v", err)
+		}
+		if b.Id != id || rd != d {
+			t.Fatalf("wrong trip, expected: %v %v, received: %v %v", id, d, b.Id, rd)
+		}
+	}
+
+	test("", "sooner", "2024-07-05T18:30:00Z")
+	test("2024-07-12T08:00:00Z", "later", "2024-07-12T08:00:00Z")
+	// Departed journey which is no longer part of any booking.
+	test("2024-06-30T08:00:00Z", "later", "2024-06-30T08:00:00Z")
+
+	b, rd, err := selectTrip(nil, "2024-06-30T08:00:00Z", now)
+	if err != nil || b.Id != "" || rd != "2024-06-30T08:00:00Z" {
+		t.Fatalf("wrong departure of the missing booking: %v, %v, %v", b, rd, err)
+	}
+	if _, _, err := selectTrip([]Booking{trip("flown", "2024-06-28T08:00:00Z")}, "", now); err == nil {
+		t.Fatalf("expected error without upcoming journey")
+	}
+}
+
+func TestValidateConcurrency(t *testing.T) {
+	test := func(n int, ok bool) {
+		if err := validateConcurrency(n); (err == nil) != ok {
+			t.Fatalf("wrong validation of %v, received: %v", n, err)
+		}
+	}
+
+	test(0, true)
+	test(5, true)
+	test(maxConcurrency, true)
+	test(-1, false)
+	test(maxConcurrency+1, false)
+}
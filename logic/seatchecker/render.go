@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+)
+
+// Render seat map as a text grid, taken seats are marked with "X" and free with ".".
+//
+//	   ABC DEF
+//	01 X.. ..X
+func renderASCII(sm SeatMap) string {
+	var sb strings.Builder
+	sb.WriteString("   ABC DEF\n")
+	for row := 1; row <= sm.Rows; row++ {
+		fmt.Fprintf(&sb, "%02d ", row)
+		for i, c := range seatColumns {
+			if i == len(seatColumns)/2 {
+				sb.WriteString(" ") // Aisle.
+			}
+			if sm.free(row, c) {
+				sb.WriteString(".")
+			} else {
+				sb.WriteString("X")
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// Dimensions of the cabin picture in pixels.
+const (
+	seatSize   = 16
+	seatGap    = 4
+	aisleWidth = 16
+)
+
+var (
+	colorCabin = color.RGBA{0xf2, 0xf2, 0xf2, 0xff}
+	colorFree  = color.RGBA{0x2e, 0x9e, 0x4f, 0xff}
+	colorTaken = color.RGBA{0xb0, 0xb0, 0xb0, 0xff}
+)
+
+// Render seat map as a PNG picture of the cabin, the nose of the plane is on the top.
+func renderPNG(sm SeatMap) ([]byte, error) {
+	half := len(seatColumns) / 2
+	w := len(seatColumns)*(seatSize+seatGap) + seatGap + aisleWidth
+	h := sm.Rows*(seatSize+seatGap) + seatGap
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), &image.Uniform{colorCabin}, image.Point{}, draw.Src)
+
+	for row := 1; row <= sm.Rows; row++ {
+		y := seatGap + (row-1)*(seatSize+seatGap)
+		for i, c := range seatColumns {
+			x := seatGap + i*(seatSize+seatGap)
+			if i >= half {
+				x += aisleWidth
+			}
+			clr := colorTaken
+			if sm.free(row, c) {
+				clr = colorFree
+			}
+			r := image.Rect(x, y, x+seatSize, y+seatSize)
+			draw.Draw(img, r, &image.Uniform{clr}, image.Point{}, draw.Src)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode png: %v", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+)
+
+func TestRenderASCII(t *testing.T) {
+	sm := newSeatMap(3, []string{"01A", "02C", "02D", "03F"})
+
+	e := "   ABC DEF\n" +
+		"01 X.. ...\n" +
+		"02 ..X X..\n" +
+		"03 ... ..X"
+	if r := renderASCII(sm); e != r {
+		t.Fatalf("wrong seat map, expected:\n%v\nreceived:\n%v", e, r)
+	}
+}
+
+func TestRenderPNG(t *testing.T) {
+	sm := newSeatMap(2, []string{"01A"})
+
+	b, err := renderPNG(sm)
+	if err != nil {
+		t.Fatalf("failed to render seat map: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("failed to decode rendered seat map: %v", err)
+	}
+
+	ew := 6*(seatSize+seatGap) + seatGap + aisleWidth
+	eh := 2*(seatSize+seatGap) + seatGap
+	if r := img.Bounds(); r.Dx() != ew || r.Dy() != eh {
+		t.Fatalf("wrong dimensions, expected: %vx%v, received: %vx%v", ew, eh, r.Dx(), r.Dy())
+	}
+
+	// Center of seats 01A and 01B.
+	y := seatGap + seatSize/2
+	if c := img.At(seatGap+seatSize/2, y); c != colorTaken {
+		t.Fatalf("wrong color of taken seat, received: %v", c)
+	}
+	if c := img.At(2*seatGap+seatSize+seatSize/2, y); c != colorFree {
+		t.Fatalf("wrong color of free seat, received: %v", c)
+	}
+}
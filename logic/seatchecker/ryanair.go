@@ -2,10 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
+	"slices"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -22,8 +24,8 @@ type BIdResp struct {
 	Items []BIdItem `json:"items"`
 }
 
-func (c Client) getBookingId(ctx context.Context, a Auth) (string, error) {
-	ctx, span := tr.Start(ctx, "get_booking_id")
+func (c Client) getBookingIds(ctx context.Context, a Auth) ([]string, error) {
+	ctx, span := tr.Start(ctx, "get_booking_ids")
 	defer span.End()
 	span.SetAttributes(attribute.String("customer_id", a.CustomerID)) // NOTE: delete after testing.
 
@@ -32,7 +34,7 @@ func (c Client) getBookingId(ctx context.Context, a Auth) (string, error) {
 		err = fmt.Errorf("failed to create path: %v", err)
 		span.RecordError(err, trace.WithStackTrace(true))
 		span.SetStatus(codes.Error, err.Error())
-		return "", err
+		return nil, err
 	}
 
 	q := url.Values{}
@@ -43,18 +45,25 @@ func (c Client) getBookingId(ctx context.Context, a Auth) (string, error) {
 		"X-Auth-Token": {a.Token},
 	}
 
-	r, err := httpsRequestGet[BIdResp](ctx, c, p, q, h)
+	r, err := request[BIdResp](ctx, c, "GET", p, withQuery(q), withHeaders(h))
 	if err != nil {
 		err = fmt.Errorf("failed to get orders: %v", err)
 		span.RecordError(err, trace.WithStackTrace(true))
 		span.SetStatus(codes.Error, err.Error())
-		return "", err
+		return nil, err
 	}
 
-	// Items only contain single item.
-	// Flights contain a single booking with multiple segments of flight.
-	id := r.Items[0].Flights[0].BookingId
-	return id, nil
+	// Every order is listed once, segments of a booking share its ID.
+	var ids []string
+	for _, i := range r.Items {
+		for _, f := range i.Flights {
+			if !slices.Contains(ids, f.BookingId) {
+				ids = append(ids, f.BookingId)
+			}
+		}
+	}
+	span.SetAttributes(attribute.Int("bookings", len(ids)))
+	return ids, nil
 }
 
 type GqlQuery[T any] struct {
@@ -67,13 +76,42 @@ type GqlResponse[T any] struct {
 }
 
 type TripInfo struct {
-	TripId       string    `json:"tripId"`
-	SessionToken string    `json:"sessionToken"`
-	Journeys     []Journey `json:"journeys"`
+	TripId       string      `json:"tripId"`
+	SessionToken string      `json:"sessionToken"`
+	Journeys     []Journey   `json:"journeys"`
+	Passengers   []Passenger `json:"passengers"`
+	Info         BookingInfo `json:"info"`
 }
 
 type Journey struct {
-	DepartUTC string `json:"departUTC"`
+	JourneyNum   int    `json:"journeyNum"` // Position of the journey in the booking, e.g. 1 for return flight.
+	DepartUTC    string `json:"departUTC"`
+	Depart       string `json:"depart"` // Local time of the origin airport.
+	ArriveUTC    string `json:"arriveUTC"`
+	Arrive       string `json:"arrive"` // Local time of the destination airport.
+	Orig         string `json:"orig"`
+	Dest         string `json:"dest"`
+	FlightNumber string `json:"flt"`
+}
+
+type BookingInfo struct {
+	Pnr string `json:"pnr"`
+}
+
+type Passenger struct {
+	PaxNum int    `json:"paxNum"`
+	Type   string `json:"type"`
+}
+
+// Infants are sitting on the lap of an adult, they do not need a seat.
+func seatedPassengers(ps []Passenger) int {
+	n := 0
+	for _, p := range ps {
+		if p.Type != "INF" {
+			n += 1
+		}
+	}
+	return n
 }
 
 type BInfo struct {
@@ -102,13 +140,30 @@ func (c Client) getTripInfo(ctx context.Context, a Auth, id string) (TripInfo, e
 			getBookingByBookingId(bookingInfo: $bookingInfo, authToken: $authToken) {
 				sessionToken
 				tripId
+				info {
+					pnr
+				}
 				journeys {
 		        	...JourneysFrag
       			}
+				passengers {
+					...PassengersFrag
+				}
 			}
 		}
 		fragment JourneysFrag on BookingJourneyResponseModelType {
+			journeyNum
 			departUTC
+			depart
+			arriveUTC
+			arrive
+			orig
+			dest
+			flt
+		}
+		fragment PassengersFrag on BookingPassengerResponseModelType {
+			paxNum
+			type
 		}
 	`
 	v := TIVars{
@@ -117,7 +172,7 @@ func (c Client) getTripInfo(ctx context.Context, a Auth, id string) (TripInfo, e
 	}
 	b := GqlQuery[TIVars]{Query: q, Variables: v}
 
-	r, err := httpsRequestPost[GqlResponse[TIData]](ctx, c, p, b)
+	r, err := request[GqlResponse[TIData]](ctx, c, "POST", p, withJSON(b))
 	if err != nil {
 		err = fmt.Errorf("failed to get booking: %v", err)
 		span.RecordError(err, trace.WithStackTrace(true))
@@ -129,6 +184,80 @@ func (c Client) getTripInfo(ctx context.Context, a Auth, id string) (TripInfo, e
 	return ti, nil
 }
 
+type RNInfo struct {
+	ReservationNumber string `json:"reservationNumber"`
+	EmailAddress      string `json:"emailAddress"`
+}
+
+type RNVars struct {
+	BookingInfo RNInfo `json:"bookingInfo"`
+}
+
+type RNData struct {
+	TI TripInfo `json:"getBookingByReservationNumber"`
+}
+
+// Manage booking flow of the website, the booking is found by its reservation number and contact email.
+func (c Client) getTripInfoByReservation(ctx context.Context, pnr string, email string) (TripInfo, error) {
+	ctx, span := tr.Start(ctx, "get_trip_info_by_reservation")
+	defer span.End()
+	span.SetAttributes(attribute.String("pnr", pnr)) // NOTE: delete after testing.
+
+	p := "api/bookingfa/en-gb/graphql"
+
+	q := `
+		query GetBookingByReservationNumber($bookingInfo: GetBookingByReservationNumberInputType) {
+			getBookingByReservationNumber(bookingInfo: $bookingInfo) {
+				sessionToken
+				tripId
+				info {
+					pnr
+				}
+				journeys {
+		        	...JourneysFrag
+      			}
+				passengers {
+					...PassengersFrag
+				}
+			}
+		}
+		fragment JourneysFrag on BookingJourneyResponseModelType {
+			journeyNum
+			departUTC
+			depart
+			arriveUTC
+			arrive
+			orig
+			dest
+			flt
+		}
+		fragment PassengersFrag on BookingPassengerResponseModelType {
+			paxNum
+			type
+		}
+	`
+	v := RNVars{RNInfo{pnr, email}}
+	b := GqlQuery[RNVars]{Query: q, Variables: v}
+
+	r, err := request[GqlResponse[RNData]](ctx, c, "POST", p, withJSON(b))
+	if err != nil {
+		err = fmt.Errorf("failed to get booking: %v", err)
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return TripInfo{}, err
+	}
+
+	ti := r.Data.TI
+	// Unknown combination of reservation number and email returns empty booking.
+	if ti.TripId == "" {
+		err = fmt.Errorf("booking %v not found", pnr)
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return TripInfo{}, err
+	}
+	return ti, nil
+}
+
 type Basket struct {
 	Id string `json:"id"`
 }
@@ -156,7 +285,7 @@ func (c Client) createBasket(ctx context.Context, ti TripInfo) (string, error) {
 	`
 	b := GqlQuery[TripInfo]{Query: q, Variables: ti}
 
-	r, err := httpsRequestPost[GqlResponse[BData]](ctx, c, p, b)
+	r, err := request[GqlResponse[BData]](ctx, c, "POST", p, withJSON(b))
 	if err != nil {
 		err = fmt.Errorf("failed to create basket: %v", err)
 		span.RecordError(err, trace.WithStackTrace(true))
@@ -169,6 +298,7 @@ func (c Client) createBasket(ctx context.Context, ti TripInfo) (string, error) {
 }
 
 type FlightInfo struct {
+	JourneyNum       int      `json:"journeyNum"`
 	UnavailableSeats []string `json:"unavailableSeats"`
 	EquipmentModel   string   `json:"equipmentModel"`
 }
@@ -181,10 +311,21 @@ type FIData struct {
 	FlightInfos []FlightInfo `json:"seats"`
 }
 
-func (c Client) getFlightInfo(ctx context.Context, id string) (FlightInfo, error) {
+// Basket unknown to the catalog, e.g. expired one of the previous run.
+type RejectedBasketError struct {
+	BasketId string
+}
+
+func (e *RejectedBasketError) Error() string {
+	return fmt.Sprintf("basket %v rejected", e.BasketId)
+}
+
+// Seats of the journey, basket of the trip contains seats of all its journeys.
+func (c Client) getFlightInfo(ctx context.Context, id string, journeyNum int) (FlightInfo, error) {
 	ctx, span := tr.Start(ctx, "get_flight_info")
 	defer span.End()
 	span.SetAttributes(attribute.String("basket_id", id)) // NOTE: delete after testing.
+	span.SetAttributes(attribute.Int("journey_num", journeyNum))
 
 	p := "api/catalogapi/en-gb/graphql"
 
@@ -195,6 +336,7 @@ func (c Client) getFlightInfo(ctx context.Context, id string) (FlightInfo, error
 			}
 		}
 		fragment SeatsResponse on SeatAvailability {
+			journeyNum
 			unavailableSeats
 			equipmentModel
 		}
@@ -202,7 +344,7 @@ func (c Client) getFlightInfo(ctx context.Context, id string) (FlightInfo, error
 	v := FIVars{id}
 	b := GqlQuery[FIVars]{Query: q, Variables: v}
 
-	r, err := httpsRequestPost[GqlResponse[FIData]](ctx, c, p, b)
+	r, err := request[GqlResponse[FIData]](ctx, c, "POST", p, withJSON(b))
 	if err != nil {
 		err = fmt.Errorf("failed to get seats: %v", err)
 		span.RecordError(err, trace.WithStackTrace(true))
@@ -210,9 +352,23 @@ func (c Client) getFlightInfo(ctx context.Context, id string) (FlightInfo, error
 		return FlightInfo{}, err
 	}
 
-	// TODO: what is this supposed to return? always first? what if i ma on the way back?
-	fi := r.Data.FlightInfos[0]
-	return fi, nil
+	// Expired basket returns no seats.
+	if len(r.Data.FlightInfos) == 0 {
+		err := &RejectedBasketError{id}
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return FlightInfo{}, err
+	}
+
+	for _, fi := range r.Data.FlightInfos {
+		if fi.JourneyNum == journeyNum {
+			return fi, nil
+		}
+	}
+	err = fmt.Errorf("basket %v does not contain seats of journey %v", id, journeyNum)
+	span.RecordError(err, trace.WithStackTrace(true))
+	span.SetStatus(codes.Error, err.Error())
+	return FlightInfo{}, err
 }
 
 type NORSeat struct {
@@ -233,7 +389,7 @@ func (c Client) getNumberOfRows(ctx context.Context, m string) (int, error) {
 	q := url.Values{}
 	q.Add("aircraftModel", m)
 
-	rs, err := httpsRequestGet[[]NORResp](ctx, c, p, q, nil)
+	rs, err := request[[]NORResp](ctx, c, "GET", p, withQuery(q))
 	if err != nil {
 		err = fmt.Errorf("failed to get seatmap: %v", err)
 		span.RecordError(err, trace.WithStackTrace(true))
@@ -241,6 +397,14 @@ func (c Client) getNumberOfRows(ctx context.Context, m string) (int, error) {
 		return 0, err
 	}
 
+	// Unknown model returns no seat map.
+	if len(rs) == 0 {
+		err = fmt.Errorf("seatmap of %v not found", m)
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+
 	// Get first response
 	r := rs[0]
 	nor := len(r.SeatRows)
@@ -266,67 +430,64 @@ func calculateEmptySeats(rows int, seats []string) EmptySeats {
 	return es
 }
 
-func (c Client) getEmptySeats(ctx context.Context, a Auth) (EmptySeats, []string, error) {
-	ctx, span := tr.Start(ctx, "ryanair_get_empty_seats")
-	defer span.End()
-	span.SetAttributes(attribute.String("customer_id", a.CustomerID)) // NOTE: delete after testing.
+// Ryanair flow split between its mobile and browser APIs.
+type Ryanair struct {
+	mobile  Client
+	browser Client
+}
 
-	throwErr := func(err error) (EmptySeats, []string, error) {
-		span.RecordError(err, trace.WithStackTrace(true))
-		span.SetStatus(codes.Error, err.Error())
-		return EmptySeats{}, nil, err
-	}
+func (r Ryanair) login(ctx context.Context, email string, password string, device string) (Auth, error) {
+	return r.mobile.accountLogin(ctx, email, password, device)
+}
 
-	log.Println("Get closest Booking ID.")
-	bookingId, err := c.getBookingId(ctx, a)
-	if err != nil {
-		err = fmt.Errorf("get booking ID failed: %v", err)
-		return throwErr(err)
-	}
-	span.AddEvent("Booking ID retrieved successfully.")
+func (r Ryanair) verify(ctx context.Context, email string, device string, token string, code string) (Auth, error) {
+	return r.mobile.verifyDevice(ctx, email, device, token, code)
+}
 
-	log.Println("Get Trip info.")
-	ti, err := c.getTripInfo(ctx, a, bookingId)
-	if err != nil {
-		err := fmt.Errorf("get trip info failed: %v", err)
-		return throwErr(err)
+func (r Ryanair) bookings(ctx context.Context, a Auth) ([]string, error) {
+	return r.browser.getBookingIds(ctx, a)
+}
+
+func (r Ryanair) trip(ctx context.Context, a Auth, id string) (TripInfo, error) {
+	return r.browser.getTripInfo(ctx, a, id)
+}
+
+func (r Ryanair) lookup(ctx context.Context, pnr string, email string) (TripInfo, error) {
+	return r.browser.getTripInfoByReservation(ctx, pnr, email)
+}
+
+// Seats are available only through a basket created for the trip.
+// Basket of the previous run is reused, new one is created only when the catalog rejects it.
+func (r Ryanair) occupiedSeats(ctx context.Context, a Auth, ti TripInfo, j Journey, ss Session) (FlightInfo, Session, error) {
+	if ss.reusable(ti) {
+		log.Println("Get Flight info of the previous basket.")
+		fi, err := r.browser.getFlightInfo(ctx, ss.BasketId, j.JourneyNum)
+		var rb *RejectedBasketError
+		if !errors.As(err, &rb) {
+			if err != nil {
+				// Basket is not known to be invalid, e.g. on timeout.
+				return FlightInfo{}, ss, fmt.Errorf("get flight info failed: %v", err)
+			}
+			return fi, ss, nil
+		}
+		log.Printf("Previous basket rejected, create new one: %v\n", err)
 	}
-	span.AddEvent("Trip info retrieved successfully.")
 
 	log.Println("Create basket.")
-	basketId, err := c.createBasket(ctx, ti)
+	basketId, err := r.browser.createBasket(ctx, ti)
 	if err != nil {
-		err = fmt.Errorf("basket creation failed: %v", err)
-		return throwErr(err)
+		return FlightInfo{}, Session{}, fmt.Errorf("basket creation failed: %v", err)
 	}
-	span.AddEvent("Basket created successfully.")
+	ss = Session{BasketId: basketId, TripId: ti.TripId}
 
 	log.Println("Get Flight info.")
-	fi, err := c.getFlightInfo(ctx, basketId)
+	fi, err := r.browser.getFlightInfo(ctx, basketId, j.JourneyNum)
 	if err != nil {
-		err = fmt.Errorf("get flight info failed: %v", err)
-		return throwErr(err)
+		return FlightInfo{}, Session{}, fmt.Errorf("get flight info failed: %v", err)
 	}
-	span.AddEvent("Flight info retrieved successfully.")
+	return fi, ss, nil
+}
 
-	log.Println("Get number of rows in the plane.")
-	nor, err := c.getNumberOfRows(ctx, fi.EquipmentModel)
-	if err != nil {
-		err = fmt.Errorf("get number of rows in the plane failed: %v", err)
-		return throwErr(err)
-	}
-	span.AddEvent("Number of rows retrieved successfully.")
-
-	log.Println("Calculate number of empty seats.")
-	es := calculateEmptySeats(nor, fi.UnavailableSeats)
-	span.AddEvent("Empty seats calculated successfully.", trace.WithAttributes(
-		attribute.Int("window", es.Window),
-		attribute.Int("middle", es.Middle),
-		attribute.Int("aisle", es.Aisle)))
-
-	var js []string
-	for _, j := range ti.Journeys {
-		js = append(js, j.DepartUTC)
-	}
-	return es, js, nil
+func (r Ryanair) seatMap(ctx context.Context, model string) (int, error) {
+	return r.browser.getNumberOfRows(ctx, model)
 }
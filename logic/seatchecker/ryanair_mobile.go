@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -14,13 +17,45 @@ type Auth struct {
 	Token      string `json:"token"`
 }
 
-func (c Client) accountLogin(ctx context.Context, email string, password string) (Auth, error) {
+type LoginError struct {
+	Code           string `json:"code"`
+	AdditionalData []struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"additionalData"`
+}
+
+// Unknown devices receive a challenge instead of the token.
+// Decoded from the full body, the excerpt of the error may be cut.
+func mfaChallenge(err error) (*MfaChallenge, bool) {
+	var he *HTTPError
+	if !errors.As(err, &he) {
+		return nil, false
+	}
+	le := LoginError{}
+	if err := json.Unmarshal(he.raw, &le); err != nil {
+		return nil, false
+	}
+	for _, d := range le.AdditionalData {
+		if d.Code == "mfaToken" && d.Message != "" {
+			return &MfaChallenge{Token: d.Message}, true
+		}
+	}
+	return nil, false
+}
+
+// Ryanair remembers verified devices by their fingerprint.
+func (c Client) accountLogin(ctx context.Context, email string, password string, device string) (Auth, error) {
 	ctx, span := tr.Start(ctx, "ryanair_account_login")
 	defer span.End()
 	span.SetAttributes(attribute.String("email", email)) // NOTE: delete after testing.
 
 	p := "usrprof/v2/accountLogin"
 
+	h := http.Header{
+		"X-Fingerprint": {device},
+	}
+
 	b := struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
@@ -29,7 +64,11 @@ func (c Client) accountLogin(ctx context.Context, email string, password string)
 		password,
 	}
 
-	a, err := httpsRequestPost[Auth](ctx, c, p, b)
+	a, err := request[Auth](ctx, c, "POST", p, withHeaders(h), withJSON(b))
+	if ch, ok := mfaChallenge(err); ok {
+		span.AddEvent("Account login requires verification.")
+		return Auth{}, ch
+	}
 	if err != nil {
 		err = fmt.Errorf("failed to get account login: %v", err)
 		span.RecordError(err, trace.WithStackTrace(true))
@@ -40,3 +79,35 @@ func (c Client) accountLogin(ctx context.Context, email string, password string)
 
 	return a, nil
 }
+
+// Verifies the device with the code Ryanair sent to the email, which logs the user in.
+func (c Client) verifyDevice(ctx context.Context, email string, device string, token string, code string) (Auth, error) {
+	ctx, span := tr.Start(ctx, "ryanair_verify_device")
+	defer span.End()
+	span.SetAttributes(attribute.String("email", email)) // NOTE: delete after testing.
+
+	p := "usrprof/v2/accountVerifications/deviceFingerprint"
+
+	h := http.Header{
+		"X-Fingerprint": {device},
+	}
+
+	b := struct {
+		MfaCode  string `json:"mfaCode"`
+		MfaToken string `json:"mfaToken"`
+	}{
+		code,
+		token,
+	}
+
+	a, err := request[Auth](ctx, c, "PUT", p, withHeaders(h), withJSON(b))
+	if err != nil {
+		err = fmt.Errorf("failed to verify device: %v", err)
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return Auth{}, err
+	}
+	span.AddEvent("Device verification successful.")
+
+	return a, nil
+}
@@ -3,10 +3,12 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -34,7 +36,7 @@ func TestAccountLogin(t *testing.T) {
 
 	// Check received response
 	c := Client{scheme: "http", fqdn: ts.URL}
-	rcvd, err := c.accountLogin(context.Background(), em, pw)
+	rcvd, err := c.accountLogin(context.Background(), em, pw, "device")
 	if err != nil {
 		t.Fatalf("failed to get account login: %v", err)
 	}
@@ -42,3 +44,80 @@ func TestAccountLogin(t *testing.T) {
 		t.Fatalf("wrong response, expected: %v, received %v", e, rcvd)
 	}
 }
+
+func TestAccountLoginChallenge(t *testing.T) {
+	em, dev := "john@doe.com", "device"
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fp := r.Header.Get("X-Fingerprint")
+		if fp != dev {
+			t.Fatalf("wrong fingerprint, received: %v", fp)
+		}
+
+		if r.Method == "PUT" {
+			rawB, _ := io.ReadAll(r.Body)
+			b := map[string]any{}
+			json.Unmarshal(rawB, &b)
+			if b["mfaToken"] != "mfa_token" || b["mfaCode"] != "123456" {
+				http.Error(w, "{}", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintln(w, `{"customerId": "customerid", "token": "token"}`)
+			return
+		}
+
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, `{"code": "Account.UnknownDeviceFingerprint", "additionalData": [{"code": "mfaToken", "message": "mfa_token"}]}`)
+	}))
+	defer ts.Close()
+
+	c := Client{scheme: "http", fqdn: ts.URL}
+	_, err := c.accountLogin(context.Background(), em, "password", dev)
+	var ch *MfaChallenge
+	if !errors.As(err, &ch) || ch.Token != "mfa_token" {
+		t.Fatalf("expected verification challenge, received: %v", err)
+	}
+
+	a, err := c.verifyDevice(context.Background(), em, dev, ch.Token, "123456")
+	if err != nil {
+		t.Fatalf("failed to verify device: %v", err)
+	}
+	if a != (Auth{"customerid", "token"}) {
+		t.Fatalf("wrong response, received: %v", a)
+	}
+
+	if _, err := c.verifyDevice(context.Background(), em, dev, ch.Token, "000000"); err == nil {
+		t.Fatalf("expected error for wrong code")
+	}
+}
+
+func TestAccountLoginInvalid(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"code": "Account.InvalidCredentials"}`, http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	c := Client{scheme: "http", fqdn: ts.URL}
+	_, err := c.accountLogin(context.Background(), "john@doe.com", "wrong", "device")
+	var ch *MfaChallenge
+	if err == nil || errors.As(err, &ch) {
+		t.Fatalf("expected login failure, received: %v", err)
+	}
+}
+
+func TestAccountLoginLongChallenge(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Token is past the excerpt of the error.
+		d := strings.Repeat(`{"code": "hint", "message": "verify this device"}, `, 20)
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"code": "Account.UnknownDeviceFingerprint", "additionalData": [%v{"code": "mfaToken", "message": "mfa_token"}]}`, d)
+	}))
+	defer ts.Close()
+
+	c := Client{scheme: "http", fqdn: ts.URL}
+	_, err := c.accountLogin(context.Background(), "john@doe.com", "password", "device")
+	var ch *MfaChallenge
+	if !errors.As(err, &ch) || ch.Token != "mfa_token" {
+		t.Fatalf("expected verification challenge, received: %v", err)
+	}
+}
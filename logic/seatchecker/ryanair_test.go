@@ -12,9 +12,9 @@ import (
 	"testing"
 )
 
-func TestGetBookingId(t *testing.T) {
+func TestGetBookingIds(t *testing.T) {
 	a := Auth{"customerid", "token"}
-	eId := "booking_id"
+	eIds := []string{"booking_id", "other_booking_id"}
 
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Check request
@@ -36,7 +36,13 @@ func TestGetBookingId(t *testing.T) {
 			Items: []BIdItem{
 				{
 					Flights: []BIdFlight{
-						{BookingId: eId},
+						{BookingId: eIds[0]},
+						{BookingId: eIds[0]},
+					},
+				},
+				{
+					Flights: []BIdFlight{
+						{BookingId: eIds[1]},
 					},
 				},
 			},
@@ -48,17 +54,23 @@ func TestGetBookingId(t *testing.T) {
 
 	// Check received response
 	c := Client{scheme: "http", fqdn: ts.URL}
-	rId, err := c.getBookingId(context.Background(), a)
+	rIds, err := c.getBookingIds(context.Background(), a)
 	if err != nil {
-		t.Fatalf("failed to get booking id: %v", err)
+		t.Fatalf("failed to get booking ids: %v", err)
 	}
-	if eId != rId {
-		t.Fatalf("wrong booking id, expected: %v, received %v", eId, rId)
+	if !reflect.DeepEqual(eIds, rIds) {
+		t.Fatalf("wrong booking ids, expected: %v, received %v", eIds, rIds)
 	}
 }
 
 func TestGetBookingById(t *testing.T) {
-	e := TripInfo{TripId: "trip_id", SessionToken: "session_token"}
+	e := TripInfo{
+		TripId:       "trip_id",
+		SessionToken: "session_token",
+		Journeys:     []Journey{{0, "2024-07-05T18:30:00Z", "2024-07-05T19:30:00.000", "2024-07-05T19:45:00Z", "2024-07-05T19:45:00.000", "DUB", "STN", "FR 1234"}},
+		Passengers:   []Passenger{{1, "ADT"}},
+		Info:         BookingInfo{Pnr: "ABC123"},
+	}
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Check request
 		rawB, _ := io.ReadAll(r.Body)
@@ -92,10 +104,52 @@ func TestGetBookingById(t *testing.T) {
 	if e.TripId != r.TripId {
 		t.Fatalf("wrong trip id, expected: %v, received %v", e.TripId, r.TripId)
 	}
+	if !reflect.DeepEqual(e.Journeys, r.Journeys) {
+		t.Fatalf("wrong journeys, expected: %v, received %v", e.Journeys, r.Journeys)
+	}
+	if e.Info.Pnr != r.Info.Pnr {
+		t.Fatalf("wrong pnr, expected: %v, received %v", e.Info.Pnr, r.Info.Pnr)
+	}
+	if !reflect.DeepEqual(e.Passengers, r.Passengers) {
+		t.Fatalf("wrong passengers, expected: %v, received %v", e.Passengers, r.Passengers)
+	}
+}
+
+func TestGetTripInfoByReservation(t *testing.T) {
+	e := TripInfo{TripId: "trip_id", SessionToken: "session_token", Info: BookingInfo{Pnr: "ABC123"}}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Check request
+		rawB, _ := io.ReadAll(r.Body)
+		b := GqlQuery[RNVars]{}
+		json.Unmarshal(rawB, &b)
+
+		rres := GqlResponse[RNData]{}
+		if b.Variables == (RNVars{RNInfo{"ABC123", "email"}}) {
+			rres.Data.TI = e
+		}
+		res, _ := json.Marshal(rres)
+		fmt.Fprintln(w, string(res))
+	}))
+	defer ts.Close()
+
+	// Check received response
+	c := Client{scheme: "http", fqdn: ts.URL}
+	r, err := c.getTripInfoByReservation(context.Background(), "ABC123", "email")
+	if err != nil {
+		t.Fatalf("failed to get booking: %v", err)
+	}
+	if !reflect.DeepEqual(e, r) {
+		t.Fatalf("wrong trip info, expected: %v, received %v", e, r)
+	}
+
+	_, err = c.getTripInfoByReservation(context.Background(), "ABC123", "other")
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found error, received: %v", err)
+	}
 }
 
 func TestCreateBasket(t *testing.T) {
-	a := TripInfo{"trip_id", "session_token", []Journey{{"depart_utc"}}}
+	a := TripInfo{TripId: "trip_id", SessionToken: "session_token", Journeys: []Journey{{DepartUTC: "depart_utc"}}}
 	e := "basket_id"
 
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -131,7 +185,7 @@ func TestCreateBasket(t *testing.T) {
 
 func TestGetSeatsQuery(t *testing.T) {
 	id := "basket_id"
-	e := FlightInfo{[]string{"01A", "01B", "01C"}, "30A"}
+	e := FlightInfo{1, []string{"01A", "01B", "01C"}, "30A"}
 
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Check request
@@ -145,7 +199,7 @@ func TestGetSeatsQuery(t *testing.T) {
 
 		// Create fake response
 		rres := GqlResponse[FIData]{
-			Data: FIData{FlightInfos: []FlightInfo{e}},
+			Data: FIData{FlightInfos: []FlightInfo{{0, []string{"02A"}, "30A"}, e}},
 		}
 
 		res, _ := json.Marshal(rres)
@@ -156,7 +210,7 @@ func TestGetSeatsQuery(t *testing.T) {
 	// Check received response
 	c := Client{scheme: "http", fqdn: ts.URL}
 
-	r, err := c.getFlightInfo(context.Background(), id)
+	r, err := c.getFlightInfo(context.Background(), id, 1)
 	if err != nil {
 		t.Fatalf("failed to query seats: %v", err)
 	}
@@ -164,6 +218,11 @@ func TestGetSeatsQuery(t *testing.T) {
 	if !reflect.DeepEqual(e, r) {
 		t.Fatalf("wrong seats, expected: %v, received: %v", e, r)
 	}
+
+	// Seats of other journeys are never returned instead.
+	if r, err := c.getFlightInfo(context.Background(), id, 2); err == nil {
+		t.Fatalf("expected error of missing journey, received: %v", r)
+	}
 }
 
 func TestGetNumberOfRows(t *testing.T) {
@@ -196,6 +255,23 @@ func TestGetNumberOfRows(t *testing.T) {
 	}
 }
 
+func TestGetNumberOfRowsEmpty(t *testing.T) {
+	test := func(body string) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, body)
+		}))
+		defer ts.Close()
+
+		c := Client{scheme: "http", fqdn: ts.URL}
+		if r, err := c.getNumberOfRows(context.Background(), "32A"); err == nil {
+			t.Fatalf("expected error of seatmap %q, received: %v", body, r)
+		}
+	}
+
+	test("")
+	test("[]")
+}
+
 func TestCalculateEmptySeats(t *testing.T) {
 	rws := 4
 
@@ -229,6 +305,74 @@ func TestCalculateEmptySeats(t *testing.T) {
 	test(ns, EmptySeats{ms, ms, ms})
 }
 
+func TestRyanairOccupiedSeats(t *testing.T) {
+	created := 0
+	valid := map[string]bool{}
+	failing := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rres any
+		switch {
+		case failing:
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		case strings.Contains(r.URL.Path, "/basketapi/"):
+			created += 1
+			id := fmt.Sprintf("basket_%v", created)
+			valid[id] = true
+			rres = GqlResponse[BData]{Data: BData{Basket: Basket{Id: id}}}
+		case strings.Contains(r.URL.Path, "/catalogapi/"):
+			rawB, _ := io.ReadAll(r.Body)
+			b := GqlQuery[FIVars]{}
+			json.Unmarshal(rawB, &b)
+			// Unknown basket is rejected with empty data.
+			fis := []FlightInfo{}
+			if valid[b.Variables.BId] {
+				fis = append(fis, FlightInfo{0, []string{"01A"}, "73H"})
+			}
+			rres = GqlResponse[FIData]{Data: FIData{FlightInfos: fis}}
+		}
+		res, _ := json.Marshal(rres)
+		fmt.Fprintln(w, string(res))
+	}))
+	defer ts.Close()
+
+	c := Client{scheme: "http", fqdn: ts.URL}
+	ry := Ryanair{mobile: c, browser: c}
+	ti := TripInfo{TripId: "trip_id", SessionToken: "session_token"}
+
+	test := func(ti TripInfo, ss Session, e Session, calls int) Session {
+		_, r, err := ry.occupiedSeats(context.Background(), Auth{}, ti, Journey{}, ss)
+		if err != nil {
+			t.Fatalf("failed to get occupied seats: %v", err)
+		}
+		if r != e {
+			t.Fatalf("wrong session, expected: %v, received: %v", e, r)
+		}
+		if created != calls {
+			t.Fatalf("wrong number of created baskets, expected: %v, received: %v", calls, created)
+		}
+		return r
+	}
+
+	ss := test(ti, Session{}, Session{"basket_1", "trip_id"}, 1)
+	ss = test(ti, ss, ss, 1)
+
+	// Basket of other trip is not reused.
+	ot := TripInfo{TripId: "other_trip", SessionToken: "other_token"}
+	test(ot, ss, Session{"basket_2", "other_trip"}, 2)
+
+	// Failing catalog keeps the basket for the next run.
+	failing = true
+	if _, r, err := ry.occupiedSeats(context.Background(), Auth{}, ti, Journey{}, ss); err == nil || r != ss || created != 2 {
+		t.Fatalf("basket recreated on failing catalog: %v, %v, created: %v", r, err, created)
+	}
+	failing = false
+
+	// Rejected basket is recreated.
+	delete(valid, "basket_1")
+	test(ti, ss, Session{"basket_3", "trip_id"}, 3)
+}
+
 func TestQueryRyanair(t *testing.T) {
 	// TODO: implement
 }
@@ -0,0 +1,77 @@
+package main
+
+import "fmt"
+
+// Seat columns of a single row, from the left window to the right window.
+var seatColumns = []string{"A", "B", "C", "D", "E", "F"}
+
+type SeatMap struct {
+	Rows  int
+	Taken map[string]bool
+}
+
+func newSeatMap(rows int, unavailable []string) SeatMap {
+	t := map[string]bool{}
+	for _, s := range unavailable {
+		t[s] = true
+	}
+	return SeatMap{Rows: rows, Taken: t}
+}
+
+// Ryanair designates seats by zero padded row and column, e.g. "01A".
+func seatId(row int, column string) string {
+	return fmt.Sprintf("%02d%s", row, column)
+}
+
+func seatType(column string) string {
+	switch column {
+	case "A", "F":
+		return "window"
+	case "B", "E":
+		return "middle"
+	default:
+		return "aisle"
+	}
+}
+
+func (sm SeatMap) free(row int, column string) bool {
+	return !sm.Taken[seatId(row, column)]
+}
+
+// Longest run of adjacent free seats in the row.
+// Seats across the aisle are considered adjacent, the way airlines seat groups together.
+func (sm SeatMap) freeRun(row int) int {
+	longest, run := 0, 0
+	for _, c := range seatColumns {
+		if !sm.free(row, c) {
+			run = 0
+			continue
+		}
+		run += 1
+		longest = max(longest, run)
+	}
+	return longest
+}
+
+// Empty seats of a single row by type of the seat.
+func (sm SeatMap) emptySeats(row int) EmptySeats {
+	es := EmptySeats{}
+	for _, c := range seatColumns {
+		if !sm.free(row, c) {
+			continue
+		}
+		switch seatType(c) {
+		case "window":
+			es.Window += 1
+		case "middle":
+			es.Middle += 1
+		case "aisle":
+			es.Aisle += 1
+		}
+	}
+	return es
+}
+
+func (es EmptySeats) add(o EmptySeats) EmptySeats {
+	return EmptySeats{es.Window + o.Window, es.Middle + o.Middle, es.Aisle + o.Aisle}
+}
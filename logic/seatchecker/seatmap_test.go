@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestFreeRun(t *testing.T) {
+	sm := newSeatMap(3, []string{"01C", "02A", "02F", "03A", "03C", "03E"})
+
+	test := func(row int, e int) {
+		if r := sm.freeRun(row); r != e {
+			t.Fatalf("wrong free run in row %v, expected: %v, received: %v", row, e, r)
+		}
+	}
+
+	test(1, 3)
+	// Seats across the aisle are adjacent.
+	test(2, 4)
+	test(3, 1)
+}
+
+func TestSeatId(t *testing.T) {
+	if r := seatId(7, "F"); r != "07F" {
+		t.Fatalf("wrong seat id, expected: 07F, received: %v", r)
+	}
+	if r := seatId(17, "A"); r != "17A" {
+		t.Fatalf("wrong seat id, expected: 17A, received: %v", r)
+	}
+}
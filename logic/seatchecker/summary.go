@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Statistics collected over the whole watch.
+type WatchStats struct {
+	Started    string     `json:"started"`
+	Polls      int        `json:"polls"`
+	Min        EmptySeats `json:"min"`
+	Max        EmptySeats `json:"max"`
+	MiddleGone string     `json:"middle_gone"` // When middle seats ran out.
+}
+
+func (ws WatchStats) record(now time.Time, es EmptySeats) WatchStats {
+	t := now.UTC().Format(time.RFC3339)
+	if ws.Polls == 0 {
+		ws.Started = t
+		ws.Min = es
+		ws.Max = es
+	}
+	ws.Polls += 1
+	ws.Min = EmptySeats{min(ws.Min.Window, es.Window), min(ws.Min.Middle, es.Middle), min(ws.Min.Aisle, es.Aisle)}
+	ws.Max = EmptySeats{max(ws.Max.Window, es.Window), max(ws.Max.Middle, es.Middle), max(ws.Max.Aisle, es.Aisle)}
+	if es.Middle == 0 && ws.MiddleGone == "" {
+		ws.MiddleGone = t
+	}
+	return ws
+}
+
+// Summary of the watch, reason describes why it ended, e.g. "finished".
+func (ws WatchStats) summaryText(fd FlightDetails, now time.Time, reason string) string {
+	name := "Watch"
+	if fTxt := fd.generateText(); fTxt != "" {
+		name += " of " + fTxt
+	}
+	if ws.Polls == 0 {
+		return fmt.Sprintf("%v %v before seats were checked", name, reason)
+	}
+
+	var d time.Duration
+	if t, err := time.Parse(time.RFC3339, ws.Started); err == nil {
+		d = now.Sub(t)
+	}
+	ls := []string{
+		fmt.Sprintf("%v %v after %v, %v polls", name, reason, formatDuration(d), ws.Polls),
+		fmt.Sprintf("Window: %v-%v, Middle: %v-%v, Aisle: %v-%v",
+			ws.Min.Window, ws.Max.Window, ws.Min.Middle, ws.Max.Middle, ws.Min.Aisle, ws.Max.Aisle),
+	}
+	if ws.MiddleGone != "" {
+		ls = append(ls, "Middle seats ran out at "+ws.MiddleGone)
+	} else {
+		ls = append(ls, "Middle seats did not run out")
+	}
+	return strings.Join(ls, "\n")
+}
+
+// Let every subscriber know the watch ended, regardless of their rules.
+func (s Seatchecker) sendSummary(ctx context.Context, e Event, now time.Time, reason string) {
+	m := Message{
+		Title: "Seatchecker: watch " + reason,
+		Text:  e.Stats.summaryText(e.Flight, now, reason),
+	}
+	if _, err := s.fanOut(ctx, e.subscribers(), m); err != nil {
+		log.Printf("Error: failed to send summary: %v\n", err)
+	}
+}
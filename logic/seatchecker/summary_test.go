@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWatchStatsRecord(t *testing.T) {
+	n := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	ws := WatchStats{}
+	for i, es := range []EmptySeats{{10, 4, 8}, {12, 2, 6}, {9, 0, 7}, {9, 1, 7}} {
+		ws = ws.record(n.Add(time.Duration(i)*10*time.Minute), es)
+	}
+
+	e := WatchStats{
+		Started:    "2024-07-01T12:00:00Z",
+		Polls:      4,
+		Min:        EmptySeats{9, 0, 6},
+		Max:        EmptySeats{12, 4, 8},
+		MiddleGone: "2024-07-01T12:20:00Z",
+	}
+	if ws != e {
+		t.Fatalf("wrong statistics, expected: %v, received: %v", e, ws)
+	}
+}
+
+func TestSummaryText(t *testing.T) {
+	n := time.Date(2024, 7, 2, 14, 5, 0, 0, time.UTC)
+	fd := FlightDetails{Number: "FR1234", Origin: "DUB", Destination: "STN"}
+	ws := WatchStats{
+		Started:    "2024-07-01T12:00:00Z",
+		Polls:      157,
+		Min:        EmptySeats{9, 0, 6},
+		Max:        EmptySeats{12, 4, 8},
+		MiddleGone: "2024-07-02T10:20:00Z",
+	}
+
+	test := func(ws WatchStats, fd FlightDetails, reason string, e string) {
+		if r := ws.summaryText(fd, n, reason); r != e {
+			t.Fatalf("wrong output, expected: %v, received: %v", e, r)
+		}
+	}
+
+	test(ws, fd, "finished", "Watch of FR1234 DUB→STN finished after 26h05m, 157 polls\n"+
+		"Window: 9-12, Middle: 0-4, Aisle: 6-8\n"+
+		"Middle seats ran out at 2024-07-02T10:20:00Z")
+	ws.MiddleGone = ""
+	test(ws, FlightDetails{}, "failed", "Watch failed after 26h05m, 157 polls\n"+
+		"Window: 9-12, Middle: 0-4, Aisle: 6-8\n"+
+		"Middle seats did not run out")
+	test(WatchStats{}, FlightDetails{}, "failed", "Watch failed before seats were checked")
+}
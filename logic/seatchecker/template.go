@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+const defaultTitle = "Seatchecker"
+
+// Data available to user supplied notification templates.
+type NotificationData struct {
+	Seats     EmptySeats // Current empty seats.
+	Previous  EmptySeats // Empty seats from the previous run.
+	Diff      SeatDiff
+	Departure string
+	Flight    FlightDetails
+	Forecast  string // Estimate when middle seats run out, e.g. "~1h40m", empty when unknown.
+	Text      string // Default notification text.
+}
+
+// Data of a typical run, templates are checked against it as they may index into the lists.
+var sampleNotificationData = NotificationData{
+	Seats:     EmptySeats{10, 8, 12},
+	Previous:  EmptySeats{10, 9, 12},
+	Diff:      SeatDiff{Taken: []string{"12B"}, Released: []string{"14E"}},
+	Departure: "2024-07-05T18:30:00Z",
+	Flight: FlightDetails{
+		Number:      "FR 1234",
+		Origin:      "DUB",
+		Destination: "STN",
+		Departure:   "2024-07-05T19:30:00.000",
+		Arrival:     "2024-07-05T20:45:00.000",
+		Pnr:         "ABC123",
+	},
+	Forecast: "~1h40m",
+	Text:     "Window: 10, Middle: 8, Aisle: 12",
+}
+
+type NotificationTemplates struct {
+	title *template.Template
+	body  *template.Template
+}
+
+func parseTemplate(name string, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	t, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %v template: %v", name, err)
+	}
+	// Parsing does not check fields, execute with sample data to catch typos.
+	if err := t.Execute(&strings.Builder{}, sampleNotificationData); err != nil {
+		return nil, fmt.Errorf("invalid %v template: %v", name, err)
+	}
+	return t, nil
+}
+
+func parseTemplates(title string, body string) (NotificationTemplates, error) {
+	tt, err := parseTemplate("title", title)
+	if err != nil {
+		return NotificationTemplates{}, err
+	}
+	bt, err := parseTemplate("body", body)
+	if err != nil {
+		return NotificationTemplates{}, err
+	}
+	return NotificationTemplates{tt, bt}, nil
+}
+
+func execute(t *template.Template, d NotificationData, fallback string) (string, error) {
+	if t == nil {
+		return fallback, nil
+	}
+	var sb strings.Builder
+	if err := t.Execute(&sb, d); err != nil {
+		return "", fmt.Errorf("failed to execute %v template: %v", t.Name(), err)
+	}
+	return sb.String(), nil
+}
+
+// Render title and body of notification, defaults are used for missing templates.
+func (nt NotificationTemplates) render(d NotificationData) (string, string, error) {
+	title, err := execute(nt.title, d, defaultTitle)
+	if err != nil {
+		return "", "", err
+	}
+	body, err := execute(nt.body, d, d.Text)
+	if err != nil {
+		return "", "", err
+	}
+	return title, body, nil
+}
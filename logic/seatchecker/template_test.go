@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func TestParseTemplates(t *testing.T) {
+	for _, tmpl := range []string{"{{.Seats.Middle", "{{.Cockpit}}", "{{.Seats.Middle.Row}}"} {
+		if _, err := parseTemplates("", tmpl); err == nil {
+			t.Fatalf("expected error for invalid template: %v", tmpl)
+		}
+		if _, err := parseTemplates(tmpl, ""); err == nil {
+			t.Fatalf("expected error for invalid template: %v", tmpl)
+		}
+	}
+
+	// Templates may index into lists, which are empty in zero data.
+	if _, err := parseTemplates("{{.Flight.Number}}", "Taken {{index .Diff.Taken 0}}"); err != nil {
+		t.Fatalf("valid template rejected: %v", err)
+	}
+}
+
+func TestRenderTemplates(t *testing.T) {
+	d := NotificationData{
+		Seats:     EmptySeats{4, 3, 2},
+		Previous:  EmptySeats{4, 5, 2},
+		Diff:      SeatDiff{Taken: []string{"12B", "14E"}},
+		Departure: "2024-07-05T18:30:00Z",
+		Forecast:  "~1h40m",
+		Text:      "default text",
+	}
+
+	test := func(title string, body string, et string, eb string) {
+		nt, err := parseTemplates(title, body)
+		if err != nil {
+			t.Fatalf("failed to parse templates: %v", err)
+		}
+		rt, rb, err := nt.render(d)
+		if err != nil {
+			t.Fatalf("failed to render templates: %v", err)
+		}
+		if et != rt || eb != rb {
+			t.Fatalf("wrong output, expected: %v/%v, received: %v/%v", et, eb, rt, rb)
+		}
+	}
+
+	test("", "", "Seatchecker", "default text")
+	test("Seats {{.Departure}}", "{{.Text}}!", "Seats 2024-07-05T18:30:00Z", "default text!")
+	test("", "Middle: {{.Seats.Middle}}{{with .Forecast}}, expected 0 in {{.}}{{end}}; taken {{len .Diff.Taken}}",
+		"Seatchecker", "Middle: 3, expected 0 in ~1h40m; taken 2")
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Conditions triggering notification.
+const (
+	conditionSeats      = "seats"      // Number of empty seats changed.
+	conditionGroup      = "group"      // Group of passengers can or can not sit together anymore.
+	conditionPreference = "preference" // Preferred seats were taken.
+)
+
+func validateConditions(cs []string) error {
+	for _, c := range cs {
+		switch c {
+		case conditionSeats, conditionGroup, conditionPreference:
+		default:
+			return fmt.Errorf("unknown notification condition: %v", c)
+		}
+	}
+	return nil
+}
+
+// Person receiving notifications about the watched booking.
+type Subscriber struct {
+	Name        string   `json:"name"`
+	NtfyServer  string   `json:"ntfy_server"` // Defaults to ntfy.sh.
+	NtfyTopic   string   `json:"ntfy_topic"`
+	NtfyToken   string   `json:"ntfy_token"`   // Access token of protected topics.
+	NotifyOn    []string `json:"notify_on"`    // Defaults to conditions of the event.
+	MiddleBelow int      `json:"middle_below"` // Only notify when less middle seats are empty, zero disables.
+	Policy      Policy   `json:"policy"`
+}
+
+// Subscribers are identified by their topic.
+func (sub Subscriber) key() string {
+	return sub.NtfyServer + "/" + sub.NtfyTopic
+}
+
+// Booking of the owner watched on behalf of its subscribers.
+type Watch struct {
+	Owner       string       `json:"owner"`
+	Subscribers []Subscriber `json:"subscribers"`
+}
+
+// Watch without subscribers notifies the topic of the event.
+func (e Event) subscribers() []Subscriber {
+	if len(e.Watch.Subscribers) > 0 {
+		return e.Watch.Subscribers
+	}
+	return []Subscriber{{Name: e.Watch.Owner, NtfyTopic: e.NtfyTopic, NtfyToken: e.NtfyToken, Policy: e.Policy}}
+}
+
+func (e Event) validateWatch() error {
+	if err := validateConditions(e.NotifyOn); err != nil {
+		return err
+	}
+	if err := e.Policy.validate(); err != nil {
+		return err
+	}
+	for _, sub := range e.Watch.Subscribers {
+		if sub.NtfyTopic == "" {
+			return fmt.Errorf("subscriber %v is missing ntfy topic", sub.Name)
+		}
+		if err := validateConditions(sub.NotifyOn); err != nil {
+			return fmt.Errorf("subscriber %v: %v", sub.Name, err)
+		}
+		if _, err := ntfyClient(sub.NtfyServer, Client{}); err != nil {
+			return fmt.Errorf("subscriber %v: %v", sub.Name, err)
+		}
+		if err := sub.Policy.validate(); err != nil {
+			return fmt.Errorf("subscriber %v: %v", sub.Name, err)
+		}
+	}
+	return nil
+}
+
+// Execution is started by /start before the watch is validated, the owner learns why it stopped right away.
+func (s Seatchecker) sendRejection(ctx context.Context, e Event, reason error) {
+	o := e.owner()
+	// Nowhere to send the rejection.
+	if _, err := ntfyClient(o.NtfyServer, s.ntfy); err != nil || o.NtfyTopic == "" {
+		return
+	}
+	m := Message{
+		Title: "Seatchecker: watch rejected",
+		Text:  fmt.Sprintf("Invalid configuration of the watch: %v", reason),
+	}
+	if _, err := s.fanOut(ctx, []Subscriber{o}, m); err != nil {
+		log.Printf("Error: failed to send rejection: %v\n", err)
+	}
+}
+
+// Notify about any change in seats and preferred seats, unless conditions are specified.
+func (sub Subscriber) wants(e Event, triggered []string, es EmptySeats) bool {
+	if sub.MiddleBelow > 0 && es.Middle >= sub.MiddleBelow {
+		return false
+	}
+	cs := sub.NotifyOn
+	if len(cs) == 0 {
+		cs = e.NotifyOn
+	}
+	if len(cs) == 0 {
+		cs = []string{conditionSeats, conditionPreference}
+	}
+	for _, t := range triggered {
+		if slices.Contains(cs, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Client for ntfy server of the subscriber, e.g. "https://ntfy.example.com".
+func ntfyClient(server string, fallback Client) (Client, error) {
+	if server == "" {
+		return fallback, nil
+	}
+	u, err := url.Parse(server)
+	if err != nil || u.Host == "" {
+		return Client{}, fmt.Errorf("invalid ntfy server: %v", server)
+	}
+	return Client{scheme: u.Scheme, fqdn: server, http: fallback.http, headers: fallback.headers}, nil
+}
+
+// Send message to every subscriber, failure of a single subscriber does not stop the others.
+// Returns subscribers which received the message.
+func (s Seatchecker) fanOut(ctx context.Context, subs []Subscriber, m Message) ([]Subscriber, error) {
+	ctx, span := tr.Start(ctx, "notifier_fan_out")
+	defer span.End()
+	span.SetAttributes(attribute.Int("subscribers", len(subs)))
+
+	var sent []Subscriber
+	var errs []error
+	for _, sub := range subs {
+		// Validated before.
+		c, _ := ntfyClient(sub.NtfyServer, s.ntfy)
+		if sub.NtfyToken != "" {
+			c = c.withDefaultHeader("Authorization", "Bearer "+sub.NtfyToken)
+		}
+		log.Printf("Send notification to subscriber: %v.\n", sub.Name)
+		if err := c.send(ctx, sub.NtfyTopic, m); err != nil {
+			errs = append(errs, fmt.Errorf("subscriber %v: %v", sub.Name, err))
+			continue
+		}
+		sent = append(sent, sub)
+	}
+	err := errors.Join(errs...)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return sent, err
+}
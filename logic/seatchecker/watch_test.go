@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSubscriberWants(t *testing.T) {
+	e := Event{NotifyOn: []string{"group"}}
+
+	test := func(sub Subscriber, triggered []string, es EmptySeats, ew bool) {
+		if w := sub.wants(e, triggered, es); w != ew {
+			t.Fatalf("wrong decision for %v on %v, expected: %v, received: %v", sub.Name, triggered, ew, w)
+		}
+	}
+
+	es := EmptySeats{10, 5, 10}
+	// Conditions of the event are used by default.
+	test(Subscriber{Name: "owner"}, []string{"seats"}, es, false)
+	test(Subscriber{Name: "owner"}, []string{"group"}, es, true)
+	test(Subscriber{Name: "partner", NotifyOn: []string{"seats"}}, []string{"seats"}, es, true)
+	// Thresholds filter out notifications.
+	test(Subscriber{Name: "kid", NotifyOn: []string{"seats"}, MiddleBelow: 5}, []string{"seats"}, es, false)
+	test(Subscriber{Name: "kid", NotifyOn: []string{"seats"}, MiddleBelow: 6}, []string{"seats"}, es, true)
+
+	// Without any conditions seats and preferred seats are watched.
+	e = Event{}
+	test(Subscriber{}, []string{"seats"}, es, true)
+	test(Subscriber{}, []string{"preference"}, es, true)
+	test(Subscriber{}, []string{"group"}, es, false)
+}
+
+func TestValidateWatch(t *testing.T) {
+	test := func(w Watch, valid bool) {
+		err := Event{Watch: w}.validateWatch()
+		if valid && err != nil {
+			t.Fatalf("valid watch rejected: %v", err)
+		}
+		if !valid && err == nil {
+			t.Fatalf("invalid watch accepted: %v", w)
+		}
+	}
+
+	test(Watch{Owner: "owner"}, true)
+	test(Watch{Subscribers: []Subscriber{{Name: "a", NtfyTopic: "a", NtfyServer: "https://ntfy.example.com"}}}, true)
+	test(Watch{Subscribers: []Subscriber{{Name: "a"}}}, false)
+	test(Watch{Subscribers: []Subscriber{{Name: "a", NtfyTopic: "a", NotifyOn: []string{"never"}}}}, false)
+	test(Watch{Subscribers: []Subscriber{{Name: "a", NtfyTopic: "a", NtfyServer: "ntfy"}}}, false)
+}
+
+func TestFanOut(t *testing.T) {
+	ok := &stubNtfy{}
+	os := httptest.NewServer(ok)
+	defer os.Close()
+	fs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer fs.Close()
+
+	s := Seatchecker{ntfy: Client{scheme: "http", fqdn: os.URL}}
+	subs := []Subscriber{
+		{Name: "owner", NtfyTopic: "owner"},
+		{Name: "broken", NtfyTopic: "broken", NtfyServer: fs.URL},
+		{Name: "partner", NtfyTopic: "partner", NtfyServer: os.URL},
+	}
+
+	sent, err := s.fanOut(context.Background(), subs, Message{Title: "title", Text: "text"})
+	if len(sent) != 2 || sent[1].Name != "partner" {
+		t.Fatalf("wrong notified subscribers, expected: owner and partner, received: %v", sent)
+	}
+	if err == nil {
+		t.Fatal("expected error of broken subscriber")
+	}
+	if len(ok.notifications) != 2 || ok.notifications[1].Topic != "partner" {
+		t.Fatalf("wrong notifications, received: %v", ok.notifications)
+	}
+}
+
+func TestFanOutToken(t *testing.T) {
+	var auth []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+	}))
+	defer ts.Close()
+
+	s := Seatchecker{ntfy: Client{scheme: "http", fqdn: ts.URL}}
+	subs := []Subscriber{
+		{Name: "protected", NtfyTopic: "protected", NtfyToken: "tk_secret"},
+		{Name: "public", NtfyTopic: "public"},
+	}
+	if _, err := s.fanOut(context.Background(), subs, Message{Title: "title", Text: "text"}); err != nil {
+		t.Fatalf("failed to notify subscribers: %v", err)
+	}
+	// Token of a subscriber is not sent to the others.
+	if len(auth) != 2 || auth[0] != "Bearer tk_secret" || auth[1] != "" {
+		t.Fatalf("wrong authorization, received: %v", auth)
+	}
+}
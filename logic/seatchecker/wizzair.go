@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Wizz Air flow, everything is served by the single backend API.
+// Login does not challenge unknown devices, so the device is not sent.
+type Wizzair struct {
+	api Client
+}
+
+type WizzLogin struct {
+	CustomerNumber string `json:"customerNumber"`
+	Token          string `json:"token"`
+}
+
+func (w Wizzair) login(ctx context.Context, email string, password string, device string) (Auth, error) {
+	ctx, span := tr.Start(ctx, "wizzair_login")
+	defer span.End()
+
+	p := "Api/customer/login"
+
+	b := struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}{
+		email,
+		password,
+	}
+
+	r, err := request[WizzLogin](ctx, w.api, "POST", p, withJSON(b))
+	if err != nil {
+		err = fmt.Errorf("failed to login: %v", err)
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return Auth{}, err
+	}
+
+	return Auth{CustomerID: r.CustomerNumber, Token: r.Token}, nil
+}
+
+type WizzBooking struct {
+	Pnr string `json:"pnr"`
+}
+
+type WizzBookings struct {
+	Bookings []WizzBooking `json:"bookings"`
+}
+
+func wizzHeaders(a Auth) http.Header {
+	return http.Header{
+		"Authorization": {"Bearer " + a.Token},
+	}
+}
+
+func (w Wizzair) bookings(ctx context.Context, a Auth) ([]string, error) {
+	ctx, span := tr.Start(ctx, "wizzair_bookings")
+	defer span.End()
+
+	p := "Api/customer/bookings"
+
+	q := url.Values{}
+	q.Add("status", "upcoming")
+
+	r, err := request[WizzBookings](ctx, w.api, "GET", p, withQuery(q), withHeaders(wizzHeaders(a)))
+	if err != nil {
+		err = fmt.Errorf("failed to get bookings: %v", err)
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	var ids []string
+	for _, b := range r.Bookings {
+		ids = append(ids, b.Pnr)
+	}
+	return ids, nil
+}
+
+type WizzFlight struct {
+	FlightNumber     string `json:"flightNumber"`
+	DepartureStation string `json:"departureStation"`
+	ArrivalStation   string `json:"arrivalStation"`
+	Std              string `json:"std"` // Local time of the departure station.
+	StdUtc           string `json:"stdUtc"`
+	Sta              string `json:"sta"` // Local time of the arrival station.
+	StaUtc           string `json:"staUtc"`
+}
+
+type WizzPassenger struct {
+	Type string `json:"passengerType"`
+}
+
+type WizzItinerary struct {
+	Pnr        string          `json:"pnr"`
+	Flights    []WizzFlight    `json:"flights"`
+	Passengers []WizzPassenger `json:"passengers"`
+}
+
+func (w Wizzair) trip(ctx context.Context, a Auth, id string) (TripInfo, error) {
+	ctx, span := tr.Start(ctx, "wizzair_trip")
+	defer span.End()
+	span.SetAttributes(attribute.String("pnr", id))
+
+	p, err := url.JoinPath("Api/booking/itinerary", id)
+	if err != nil {
+		err = fmt.Errorf("failed to create path: %v", err)
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return TripInfo{}, err
+	}
+
+	r, err := request[WizzItinerary](ctx, w.api, "GET", p, withHeaders(wizzHeaders(a)))
+	if err != nil {
+		err = fmt.Errorf("failed to get itinerary: %v", err)
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return TripInfo{}, err
+	}
+
+	// Wizz Air passenger types are named in full.
+	types := map[string]string{"adult": "ADT", "child": "CHD", "infant": "INF"}
+
+	ti := TripInfo{TripId: r.Pnr, Info: BookingInfo{Pnr: r.Pnr}}
+	for i, f := range r.Flights {
+		ti.Journeys = append(ti.Journeys, Journey{
+			JourneyNum:   i,
+			DepartUTC:    f.StdUtc,
+			Depart:       f.Std,
+			ArriveUTC:    f.StaUtc,
+			Arrive:       f.Sta,
+			Orig:         f.DepartureStation,
+			Dest:         f.ArrivalStation,
+			FlightNumber: f.FlightNumber,
+		})
+	}
+	for i, ps := range r.Passengers {
+		ti.Passengers = append(ti.Passengers, Passenger{PaxNum: i + 1, Type: types[ps.Type]})
+	}
+	return ti, nil
+}
+
+type WizzSeats struct {
+	Aircraft string   `json:"aircraft"`
+	Occupied []string `json:"occupied"`
+}
+
+// Seats are queried by the reservation number and the flight, there is no session to keep.
+func (w Wizzair) occupiedSeats(ctx context.Context, a Auth, ti TripInfo, j Journey, ss Session) (FlightInfo, Session, error) {
+	ctx, span := tr.Start(ctx, "wizzair_occupied_seats")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("pnr", ti.TripId),
+		attribute.String("flight_number", j.FlightNumber))
+
+	p := "Api/booking/seats"
+
+	b := struct {
+		Pnr              string `json:"pnr"`
+		FlightNumber     string `json:"flightNumber"`
+		DepartureStation string `json:"departureStation"`
+	}{
+		ti.TripId,
+		j.FlightNumber,
+		j.Orig,
+	}
+
+	r, err := request[WizzSeats](ctx, w.api, "POST", p, withHeaders(wizzHeaders(a)), withJSON(b))
+	if err != nil {
+		err = fmt.Errorf("failed to get seats: %v", err)
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return FlightInfo{}, Session{}, err
+	}
+
+	// Wizz Air does not pad rows, e.g. "7A".
+	fi := FlightInfo{JourneyNum: j.JourneyNum, EquipmentModel: r.Aircraft}
+	for _, s := range r.Occupied {
+		n, err := normalizeSeat(s)
+		if err != nil {
+			continue
+		}
+		fi.UnavailableSeats = append(fi.UnavailableSeats, n)
+	}
+	return fi, Session{}, nil
+}
+
+type WizzSeatMap struct {
+	Rows []struct {
+		Number int `json:"number"`
+	} `json:"rows"`
+}
+
+func (w Wizzair) seatMap(ctx context.Context, model string) (int, error) {
+	ctx, span := tr.Start(ctx, "wizzair_seat_map")
+	defer span.End()
+	span.SetAttributes(attribute.String("model", model))
+
+	p := "Api/asset/seatmap"
+
+	q := url.Values{}
+	q.Add("aircraft", model)
+
+	r, err := request[WizzSeatMap](ctx, w.api, "GET", p, withQuery(q))
+	if err != nil {
+		err = fmt.Errorf("failed to get seatmap: %v", err)
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+
+	return len(r.Rows), nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Stand-in for the Wizz Air API, serving a return booking of a plane with 4 rows.
+type stubWizzair struct {
+	occupied []string
+	byFlight map[string][]string // Occupied seats of the flight, overrides occupied.
+}
+
+var wizzFlights = []WizzFlight{
+	{
+		FlightNumber:     "W6 2201",
+		DepartureStation: "BUD",
+		ArrivalStation:   "LTN",
+		Std:              "2024-07-05T06:10:00",
+		StdUtc:           "2024-07-05T04:10:00Z",
+		Sta:              "2024-07-05T07:35:00",
+		StaUtc:           "2024-07-05T06:35:00Z",
+	},
+	{
+		FlightNumber:     "W6 2202",
+		DepartureStation: "LTN",
+		ArrivalStation:   "BUD",
+		Std:              "2024-07-12T08:20:00",
+		StdUtc:           "2024-07-12T07:20:00Z",
+		Sta:              "2024-07-12T11:45:00",
+		StaUtc:           "2024-07-12T09:45:00Z",
+	},
+}
+
+func (sw *stubWizzair) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasSuffix(r.URL.Path, "/login") && !strings.HasSuffix(r.URL.Path, "/seats") &&
+		!strings.HasSuffix(r.URL.Path, "/seatmap") && r.Header.Get("Authorization") != "Bearer token" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var res any
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/customer/login"):
+		res = WizzLogin{CustomerNumber: "customer", Token: "token"}
+	case strings.HasSuffix(r.URL.Path, "/customer/bookings"):
+		res = WizzBookings{Bookings: []WizzBooking{{Pnr: "XYZ789"}}}
+	case strings.Contains(r.URL.Path, "/booking/itinerary/"):
+		res = WizzItinerary{
+			Pnr:        "XYZ789",
+			Flights:    wizzFlights,
+			Passengers: []WizzPassenger{{"adult"}, {"infant"}},
+		}
+	case strings.HasSuffix(r.URL.Path, "/booking/seats"):
+		b := struct {
+			Pnr              string `json:"pnr"`
+			FlightNumber     string `json:"flightNumber"`
+			DepartureStation string `json:"departureStation"`
+		}{}
+		json.NewDecoder(r.Body).Decode(&b)
+		if !slices.ContainsFunc(wizzFlights, func(f WizzFlight) bool {
+			return f.FlightNumber == b.FlightNumber && f.DepartureStation == b.DepartureStation
+		}) {
+			http.NotFound(w, r)
+			return
+		}
+		os := sw.occupied
+		if s, ok := sw.byFlight[b.FlightNumber]; ok {
+			os = s
+		}
+		res = WizzSeats{Aircraft: "321", Occupied: os}
+	case strings.HasSuffix(r.URL.Path, "/asset/seatmap"):
+		res = WizzSeatMap{Rows: make([]struct {
+			Number int `json:"number"`
+		}, 4)}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	b, _ := json.Marshal(res)
+	fmt.Fprintln(w, string(b))
+}
+
+func newTestWizzair(t *testing.T, sw *stubWizzair) Wizzair {
+	ws := httptest.NewServer(sw)
+	t.Cleanup(ws.Close)
+	return Wizzair{api: Client{scheme: "http", fqdn: ws.URL}}
+}
+
+func TestWizzairTrip(t *testing.T) {
+	ctx := context.Background()
+	w := newTestWizzair(t, &stubWizzair{})
+
+	a, err := w.login(ctx, "email", "password", "device")
+	if err != nil {
+		t.Fatalf("failed to login: %v", err)
+	}
+
+	ids, err := w.bookings(ctx, a)
+	if err != nil {
+		t.Fatalf("failed to get bookings: %v", err)
+	}
+	if !reflect.DeepEqual(ids, []string{"XYZ789"}) {
+		t.Fatalf("wrong bookings, received: %v", ids)
+	}
+
+	ti, err := w.trip(ctx, a, ids[0])
+	if err != nil {
+		t.Fatalf("failed to get trip: %v", err)
+	}
+	e := Journey{
+		JourneyNum:   1,
+		DepartUTC:    "2024-07-12T07:20:00Z",
+		Depart:       "2024-07-12T08:20:00",
+		ArriveUTC:    "2024-07-12T09:45:00Z",
+		Arrive:       "2024-07-12T11:45:00",
+		Orig:         "LTN",
+		Dest:         "BUD",
+		FlightNumber: "W6 2202",
+	}
+	if len(ti.Journeys) != 2 || ti.Journeys[1] != e {
+		t.Fatalf("wrong journeys, expected: %v, received: %v", e, ti.Journeys)
+	}
+	if n := seatedPassengers(ti.Passengers); n != 1 {
+		t.Fatalf("wrong number of seated passengers, expected: 1, received: %v", n)
+	}
+	if ti.Info.Pnr != "XYZ789" {
+		t.Fatalf("wrong pnr, received: %v", ti.Info.Pnr)
+	}
+
+	if _, err := w.bookings(ctx, Auth{Token: "wrong"}); err == nil {
+		t.Fatalf("expected error for unauthorized request")
+	}
+}
+
+func TestWizzairOccupiedSeats(t *testing.T) {
+	ctx := context.Background()
+	sw := &stubWizzair{
+		occupied: []string{"1A", "2b", "10C", "bogus"},
+		byFlight: map[string][]string{"W6 2202": {"3D"}},
+	}
+	w := newTestWizzair(t, sw)
+	ti := TripInfo{TripId: "XYZ789"}
+
+	test := func(j Journey, e FlightInfo) {
+		fi, _, err := w.occupiedSeats(ctx, Auth{}, ti, j, Session{})
+		if err != nil {
+			t.Fatalf("failed to get occupied seats: %v", err)
+		}
+		if !reflect.DeepEqual(fi, e) {
+			t.Fatalf("wrong flight info, expected: %v, received: %v", e, fi)
+		}
+	}
+
+	test(Journey{Orig: "BUD", FlightNumber: "W6 2201"},
+		FlightInfo{UnavailableSeats: []string{"01A", "02B", "10C"}, EquipmentModel: "321"})
+	// Seats of the return flight.
+	test(Journey{JourneyNum: 1, Orig: "LTN", FlightNumber: "W6 2202"},
+		FlightInfo{JourneyNum: 1, UnavailableSeats: []string{"03D"}, EquipmentModel: "321"})
+
+	if _, _, err := w.occupiedSeats(ctx, Auth{}, ti, Journey{Orig: "DUB", FlightNumber: "W6 9999"}, Session{}); err == nil {
+		t.Fatalf("expected error for flight outside of the booking")
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Part of the cabin, either a range of rows or over-wing exit rows of the aircraft.
+type Zone struct {
+	Name  string `json:"name"`
+	From  int    `json:"from"`
+	To    int    `json:"to"` // Zero means until the last row.
+	Exits bool   `json:"exits"`
+}
+
+// Rows are assigned to the first zone they match, therefore exits precede the row ranges.
+var defaultZones = []Zone{
+	{Name: "front", From: 1, To: 5},
+	{Name: "exits", Exits: true},
+	{Name: "middle", From: 6, To: 15},
+	{Name: "rear", From: 16},
+}
+
+// Over-wing exit rows by equipment model.
+var exitRows = map[string][]int{
+	"73H": {16, 17}, // Boeing 737-800.
+	"7M8": {16, 17}, // Boeing 737-8200.
+	"320": {10, 11}, // Airbus A320.
+}
+
+type ZoneSeats struct {
+	Name  string     `json:"name"`
+	Seats EmptySeats `json:"seats"`
+}
+
+func validateZones(zs []Zone) error {
+	for _, z := range zs {
+		if z.Name == "" {
+			return fmt.Errorf("zone is missing name")
+		}
+		if z.Exits {
+			continue
+		}
+		if z.From < 1 || (z.To != 0 && z.To < z.From) {
+			return fmt.Errorf("invalid rows of zone %v: %v-%v", z.Name, z.From, z.To)
+		}
+	}
+	return nil
+}
+
+func (z Zone) contains(row int, model string) bool {
+	if z.Exits {
+		return slices.Contains(exitRows[model], row)
+	}
+	return row >= z.From && (z.To == 0 || row <= z.To)
+}
+
+// Count empty seats of every zone, rows not matching any zone are skipped.
+func calculateZoneSeats(sm SeatMap, model string, zs []Zone) []ZoneSeats {
+	if len(zs) == 0 {
+		zs = defaultZones
+	}
+
+	r := make([]ZoneSeats, len(zs))
+	for i, z := range zs {
+		r[i].Name = z.Name
+	}
+	for row := 1; row <= sm.Rows; row++ {
+		i := slices.IndexFunc(zs, func(z Zone) bool { return z.contains(row, model) })
+		if i == -1 {
+			continue
+		}
+		r[i].Seats = r[i].Seats.add(sm.emptySeats(row))
+	}
+	return r
+}
+
+func zoneText(zss []ZoneSeats) string {
+	var ls []string
+	for _, zs := range zss {
+		ls = append(ls, fmt.Sprintf("%v: %v", zs.Name, zs.Seats.generateText()))
+	}
+	return strings.Join(ls, "\n")
+}
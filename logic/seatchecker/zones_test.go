@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCalculateZoneSeats(t *testing.T) {
+	us := []string{"01A", "02B", "06C", "16A", "16F", "17B", "20D", "20E"}
+	sm := newSeatMap(20, us)
+
+	test := func(model string, zs []Zone, e []ZoneSeats) {
+		r := calculateZoneSeats(sm, model, zs)
+		if !reflect.DeepEqual(e, r) {
+			t.Fatalf("wrong zone seats, expected: %v, received: %v", e, r)
+		}
+	}
+
+	test("73H", nil, []ZoneSeats{
+		{"front", EmptySeats{9, 9, 10}},
+		{"exits", EmptySeats{2, 3, 4}},
+		{"middle", EmptySeats{20, 20, 19}},
+		{"rear", EmptySeats{6, 5, 5}},
+	})
+	// Exit rows of unknown aircraft belong to the rear.
+	test("unknown", nil, []ZoneSeats{
+		{"front", EmptySeats{9, 9, 10}},
+		{"exits", EmptySeats{0, 0, 0}},
+		{"middle", EmptySeats{20, 20, 19}},
+		{"rear", EmptySeats{8, 8, 9}},
+	})
+	// Rows outside of configured zones are skipped.
+	test("73H", []Zone{{Name: "first", From: 1, To: 1}, {Name: "last", From: 20}}, []ZoneSeats{
+		{"first", EmptySeats{1, 2, 2}},
+		{"last", EmptySeats{2, 1, 1}},
+	})
+}
+
+func TestValidateZones(t *testing.T) {
+	if err := validateZones(defaultZones); err != nil {
+		t.Fatalf("default zones are invalid: %v", err)
+	}
+	if err := validateZones([]Zone{{From: 1, To: 5}}); err == nil {
+		t.Fatal("expected error for zone without name")
+	}
+	if err := validateZones([]Zone{{Name: "back", From: 10, To: 5}}); err == nil {
+		t.Fatal("expected error for zone with invalid rows")
+	}
+}
+
+func TestZoneText(t *testing.T) {
+	e := "front: Window: 4, Middle: 0, Aisle: 2\nrear: Window: 1, Middle: 2, Aisle: 3"
+	r := zoneText([]ZoneSeats{{"front", EmptySeats{4, 0, 2}}, {"rear", EmptySeats{1, 2, 3}}})
+	if e != r {
+		t.Fatalf("wrong output, expected: %v, received: %v", e, r)
+	}
+}